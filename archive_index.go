@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// loadArchiveIndex reads the UUIDs recorded in the archive index, one per line.
+// A missing index is treated as empty.
+func loadArchiveIndex(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]bool{}, nil
+		}
+		return nil, fmt.Errorf("failed to read archive index: %w", err)
+	}
+
+	index := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		if uuid := strings.TrimSpace(line); uuid != "" {
+			index[uuid] = true
+		}
+	}
+	return index, nil
+}
+
+// appendArchiveIndex records the UUIDs of newly archived articles. Articles
+// without a UUID cannot be looked up later, so they are left out.
+func appendArchiveIndex(path string, articles []Article) (err error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create index directory %s: %w", dir, err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open archive index: %w", err)
+	}
+	defer closeWithError(file, &err)
+
+	ew := &errWriter{w: file}
+	for _, article := range articles {
+		if article.UUID != "" {
+			fmt.Fprintf(ew, "%s\n", article.UUID)
+		}
+	}
+	return ew.err
+}
+
+// rebuildArchiveIndex recreates the archive index from the text files in dir and
+// returns the number of UUIDs it now holds
+func rebuildArchiveIndex(path, dir string, atomic bool) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return 0, err
+	}
+	// Include archives compressed by COMPRESS_OLDER_THAN
+	compressed, err := filepath.Glob(filepath.Join(dir, "*.txt.gz"))
+	if err != nil {
+		return 0, err
+	}
+	files = append(files, compressed...)
+
+	all := make(map[string]bool)
+	for _, file := range files {
+		uuids, err := readArticleUUIDs(file)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", file, err)
+		}
+		for uuid := range uuids {
+			if uuid != "" {
+				all[uuid] = true
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(all))
+	for uuid := range all {
+		sorted = append(sorted, uuid)
+	}
+	sort.Strings(sorted)
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return 0, fmt.Errorf("failed to create index directory %s: %w", dir, err)
+		}
+	}
+	err = writeOutput(path, atomic, func(w io.Writer) {
+		for _, uuid := range sorted {
+			fmt.Fprintf(w, "%s\n", uuid)
+		}
+	})
+	return len(sorted), err
+}
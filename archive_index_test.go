@@ -0,0 +1,88 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArchiveIndexSkipsArchived(t *testing.T) {
+	inTempDir(t)
+	t.Setenv("ARCHIVE_INDEX", "archive.idx")
+	writeFile(t, "archive.idx", "u1\n")
+	cfg := testConfig(t)
+	cfg.StateFile = "state.json"
+
+	fetched := []Article{testArticle("u2", "2024-05-01T10:00:00Z"), testArticle("u1", "2024-05-01T09:00:00Z")}
+	if code, _ := deliverArticles(context.Background(), cfg, false, State{}, fetched, nil, true, 0, cfg.Location); code != 0 {
+		t.Fatalf("deliverArticles() = %d, want 0", code)
+	}
+
+	files, _ := filepath.Glob(filepath.Join("fetched_articles", "*.txt"))
+	if len(files) != 1 {
+		t.Fatalf("wrote %v, want one file", files)
+	}
+	content := readFile(t, files[0])
+	if !strings.Contains(content, "UUID: u2\n") || strings.Contains(content, "UUID: u1\n") {
+		t.Errorf("digest should hold only u2:\n%s", content)
+	}
+	if got := readFile(t, "archive.idx"); got != "u1\nu2\n" {
+		t.Errorf("index = %q, want u1 and the newly archived u2", got)
+	}
+}
+
+func TestArchiveIndexSkipsEmptyUUID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.idx")
+	anonymous := testArticle("", "2024-05-01T09:00:00Z")
+	if err := appendArchiveIndex(path, []Article{testArticle("u1", "2024-05-01T10:00:00Z"), anonymous}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "u1\n" {
+		t.Errorf("index = %q, want only u1", got)
+	}
+}
+
+func TestRebuildArchiveIndex(t *testing.T) {
+	inTempDir(t)
+	cfg := testConfig(t)
+	if err := os.MkdirAll("fetched_articles", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeArticlesToFile([]Article{testArticle("u3", "2024-05-02T10:00:00Z"), testArticle("u1", "2024-05-02T09:00:00Z")},
+		filepath.Join("fetched_articles", "a.txt"), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An archive compressed by COMPRESS_OLDER_THAN counts too
+	plain := filepath.Join(t.TempDir(), "b.txt")
+	if err := writeArticlesToFile([]Article{testArticle("u2", "2024-05-01T10:00:00Z")}, plain, cfg); err != nil {
+		t.Fatal(err)
+	}
+	gz, err := os.Create(filepath.Join("fetched_articles", "b.txt.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(gz)
+	zw.Write([]byte(readFile(t, plain)))
+	zw.Close()
+	gz.Close()
+
+	if code := cmdDigest(context.Background(), []string{"-rebuild-index"}); code != 1 {
+		t.Errorf("-rebuild-index without ARCHIVE_INDEX exited %d, want 1", code)
+	}
+
+	// A stale entry is dropped by the rebuild
+	t.Setenv("ARCHIVE_INDEX", filepath.Join("index", "archive.idx"))
+	os.Mkdir("index", 0755)
+	writeFile(t, filepath.Join("index", "archive.idx"), "gone\n")
+	if code := cmdDigest(context.Background(), []string{"-rebuild-index"}); code != 0 {
+		t.Fatalf("-rebuild-index exited %d, want 0", code)
+	}
+	if got := readFile(t, filepath.Join("index", "archive.idx")); got != "u1\nu2\nu3\n" {
+		t.Errorf("rebuilt index = %q, want u1, u2 and u3", got)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// usageError marks a flag parsing failure that the flag package already reported
+type usageError struct{ error }
+
+// loadCommandConfig reads the environment configuration and applies the command's
+// flags on top. setup may register flags specific to the command.
+func loadCommandConfig(name string, args []string, setup func(fs *flag.FlagSet)) (Config, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	cfg.bindFlags(fs)
+	if setup != nil {
+		setup(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, usageError{err}
+	}
+	return cfg, nil
+}
+
+// commandError reports err and returns the matching exit code
+func commandError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if errors.As(err, new(usageError)) {
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	return 1
+}
+
+// cmdDigest fetches, emails and saves new articles
+func cmdDigest(ctx context.Context, args []string) int {
+	var preview bool
+	var previewInput string
+	var previewPort int
+	var rebuildIndex bool
+	var dryRun, explain bool
+	var force bool
+	var chat chatFlags
+	cfg, err := loadCommandConfig("leetcode-articles-fetcher", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&preview, "preview", false, "serve the rendered email locally instead of running the digest")
+		fs.StringVar(&previewInput, "input", "", "articles JSON for --preview (default: latest JSON in fetched_articles)")
+		fs.IntVar(&previewPort, "port", 8080, "port for --preview")
+		fs.BoolVar(&rebuildIndex, "rebuild-index", false, "recreate ARCHIVE_INDEX from the files in fetched_articles and exit")
+		fs.BoolVar(&dryRun, "dry-run", false, "fetch and list new articles without emailing, writing files or saving state")
+		fs.BoolVar(&explain, "explain", false, "describe the cutoff, filters, outputs and delivery of a run, then exit without making any request")
+		fs.BoolVar(&force, "force", false, "run even if the last run was within MIN_RUN_INTERVAL")
+		chat.bind(fs)
+	})
+	if err != nil {
+		return commandError(err)
+	}
+	cfg.Force = force
+	if err := chat.apply(&cfg); err != nil {
+		return commandError(err)
+	}
+
+	if rebuildIndex {
+		if cfg.ArchiveIndex == "" {
+			return commandError(errors.New("--rebuild-index requires ARCHIVE_INDEX to be set"))
+		}
+		n, err := rebuildArchiveIndex(cfg.ArchiveIndex, "fetched_articles", cfg.AtomicWrite)
+		if err != nil {
+			return commandError(fmt.Errorf("failed to rebuild archive index: %w", err))
+		}
+		fmt.Printf("✓ Rebuilt %s with %d archived articles\n", cfg.ArchiveIndex, n)
+		return 0
+	}
+
+	if preview {
+		if err := runPreview(ctx, cfg, previewInput, previewPort); err != nil {
+			return commandError(err)
+		}
+		return 0
+	}
+
+	if explain {
+		if err := explainRun(os.Stdout, cfg, cfg.EmailEnabled() && !dryRun, time.Now()); err != nil {
+			return commandError(err)
+		}
+		return 0
+	}
+
+	if dryRun {
+		cfg.DryRun = true
+		return runDigest(ctx, cfg, false)
+	}
+	return runDigest(ctx, cfg, cfg.EmailEnabled())
+}
+
+// cmdFetch fetches new articles and writes them to files only
+func cmdFetch(ctx context.Context, args []string) int {
+	var force bool
+	cfg, err := loadCommandConfig("fetch", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&force, "force", false, "run even if the last run was within MIN_RUN_INTERVAL")
+	})
+	if err != nil {
+		return commandError(err)
+	}
+	cfg.Force = force
+	cfg.EnableFileOutput = true
+	return runDigest(ctx, cfg, false)
+}
+
+// cmdSend delivers a previously saved JSON digest by email and to the configured
+// notifiers, without fetching or touching state. This allows re-sending a digest
+// after fixing email credentials.
+func cmdSend(ctx context.Context, args []string) int {
+	var input string
+	var chat chatFlags
+	cfg, err := loadCommandConfig("send", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&input, "input", "", "JSON file with the articles to send, as written by the JSON output")
+		chat.bind(fs)
+	})
+	if err != nil {
+		return commandError(err)
+	}
+	if err := chat.apply(&cfg); err != nil {
+		return commandError(err)
+	}
+
+	if input == "" {
+		return commandError(errors.New("send requires --input <file>"))
+	}
+	emailEnabled := cfg.EmailEnabled()
+	if !emailEnabled && len(cfg.Notifiers(nil)) == 0 {
+		return commandError(errors.New("nothing to send to; configure email (FROM_EMAIL, RECIPIENTS and SENDGRID_API_KEY, or SMTP_HOST with EMAIL_PROVIDER=smtp, or MAILGUN_DOMAIN and MAILGUN_API_KEY with EMAIL_PROVIDER=mailgun), " +
+			"-slack, -discord or READ_LATER_SERVICE"))
+	}
+
+	articles, err := readArticlesJSON(input)
+	if err != nil {
+		return commandError(err)
+	}
+	if err := validateArticles(articles); err != nil {
+		return commandError(fmt.Errorf("invalid input %s: %w", input, err))
+	}
+
+	fmt.Printf("Sending %d articles from %s...\n", len(articles), input)
+	exitCode := 0
+	if emailEnabled {
+		if err := resendEmail(ctx, cfg, articles); err != nil {
+			exitCode = commandError(fmt.Errorf("failed to send email: %w", err))
+		}
+	}
+
+	// The state is only read, so articles already saved for later are skipped
+	// but the ones saved now are not recorded
+	state, err := readState(cfg.StateFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	for _, notifier := range cfg.Notifiers(newSavedURLs(state.SavedURLs)) {
+		if err := deliverNotification(ctx, notifier, cfg, articles); err != nil {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// resendEmail emails articles to every recipient, reporting the rejected ones.
+// It fails only when no recipient accepted the message.
+func resendEmail(ctx context.Context, cfg Config, articles []Article) error {
+	recipients := cfg.ToEmails
+	err := sendDigestEmail(ctx, cfg, articles, recipients, cfg.Location)
+
+	var recipientErr *RecipientError
+	if errors.As(err, &recipientErr) && !recipientErr.AllRejected() {
+		for email, rejection := range recipientErr.Rejected {
+			fmt.Fprintf(os.Stderr, "Warning: Recipient %s was rejected: %v\n", email, rejection)
+		}
+		recipients, err = recipientErr.Accepted, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Successfully sent email to: %s\n", strings.Join(recipients, ", "))
+	return nil
+}
+
+// chatFlags are the -slack and -discord flags of the commands that deliver a digest
+type chatFlags struct {
+	slack, discord bool
+}
+
+func (f *chatFlags) bind(fs *flag.FlagSet) {
+	fs.BoolVar(&f.slack, "slack", false, "also post the digest to SLACK_WEBHOOK_URL")
+	fs.BoolVar(&f.discord, "discord", false, "also post the digest to DISCORD_WEBHOOK_URL")
+}
+
+// apply enables the chosen channels, each of which needs its webhook configured
+func (f chatFlags) apply(cfg *Config) error {
+	if f.slack {
+		if cfg.SlackWebhookURL == "" {
+			return errors.New("-slack requires SLACK_WEBHOOK_URL to be set")
+		}
+		cfg.PostToSlack = true
+	}
+	if f.discord {
+		if cfg.DiscordWebhookURL == "" {
+			return errors.New("-discord requires DISCORD_WEBHOOK_URL to be set")
+		}
+		cfg.PostToDiscord = true
+	}
+	return nil
+}
+
+// cmdCheck runs diagnostics on the configuration, state, output directory and source
+func cmdCheck(ctx context.Context, args []string) int {
+	cfg, err := loadCommandConfig("check", args, nil)
+	if err != nil {
+		return commandError(err)
+	}
+
+	failed := false
+	report := func(name string, err error, detail string) {
+		if err != nil {
+			failed = true
+			fmt.Printf("✗ %s: %v\n", name, err)
+			return
+		}
+		fmt.Printf("✓ %s: %s\n", name, detail)
+	}
+
+	report("Configuration", nil, "loaded")
+
+	if cfg.EmailEnabled() {
+		report("Email", nil, fmt.Sprintf("%d recipients via %s", len(cfg.ToEmails), cfg.EmailProvider))
+	} else {
+		fmt.Println("- Email: disabled (FROM_EMAIL, RECIPIENTS and provider credentials are required)")
+	}
+
+	state, err := readState(cfg.StateFile)
+	detail := "no previous run"
+	if !state.LastProcessed.IsZero() {
+		detail = "last processed " + state.LastProcessed.Format(time.RFC3339)
+	}
+	report("State file "+cfg.StateFile, err, detail)
+
+	report("Output directory", checkOutputDir("fetched_articles"), "writable")
+
+	source, err := newArticleSource(cfg.Source, cfg.ClientOptions())
+	if err == nil {
+		err = checkSource(ctx, source)
+	}
+	report("Source", err, "reachable")
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// checkOutputDir verifies that files can be created in dir
+func checkOutputDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".check-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkSource makes the smallest possible request against the source
+func checkSource(ctx context.Context, source ArticleSource) error {
+	switch s := source.(type) {
+	case LeetCodeSource:
+		// A single attempt: the point is to see whether the API answers right now
+		_, _, err := fetchDiscussPage(ctx, 1, 0, s.Options)
+		return err
+	case FileSource:
+		_, err := readArticlesJSON(s.Path)
+		return err
+	default:
+		return nil
+	}
+}
+
+// cmdVersion prints the version
+func cmdVersion(_ context.Context, args []string) int {
+	fmt.Printf("leetcode-articles-fetcher %s\n", appVersion)
+	return 0
+}
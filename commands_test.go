@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeArticlesFile writes articles as a JSON digest for the send command
+func writeArticlesFile(t *testing.T, articles []Article) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "articles.json")
+	if err := writeArticlesToJSON(articles, path, Config{AtomicWrite: true}); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCmdSend(t *testing.T) {
+	articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z"), testArticle("u2", "2024-05-01T09:00:00Z")}
+	input := writeArticlesFile(t, articles)
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		wantCode int
+		want     []string // requests made, by host path
+	}{
+		{
+			name: "email only",
+			env:  map[string]string{"FROM_EMAIL": "bot@example.com", "RECIPIENTS": "a@example.com", "SENDGRID_API_KEY": "key"},
+			want: []string{"/v3/mail/send"},
+		},
+		{
+			name: "notifier only",
+			env:  map[string]string{"SLACK_WEBHOOK_URL": "https://hooks.slack.com/services/T/B/X"},
+			args: []string{"-slack"},
+			want: []string{"/services/T/B/X"},
+		},
+		{
+			name: "email and notifiers",
+			env: map[string]string{
+				"FROM_EMAIL": "bot@example.com", "RECIPIENTS": "a@example.com", "SENDGRID_API_KEY": "key",
+				"DISCORD_WEBHOOK_URL": "https://discord.com/api/webhooks/1/x",
+				"READ_LATER_SERVICE":  "instapaper", "INSTAPAPER_USERNAME": "me",
+			},
+			args: []string{"-discord"},
+			want: []string{"/api/add", "/api/add", "/api/webhooks/1/x", "/v3/mail/send"},
+		},
+		{
+			name:     "nothing configured",
+			wantCode: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			var mu sync.Mutex
+			var requests []string
+			routeDefaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests = append(requests, r.URL.Path)
+				mu.Unlock()
+				switch {
+				case strings.HasPrefix(r.URL.Path, "/v3/"):
+					w.WriteHeader(http.StatusAccepted)
+				case r.URL.Path == "/api/add":
+					w.WriteHeader(http.StatusCreated)
+				case strings.HasPrefix(r.URL.Path, "/api/webhooks/"):
+					w.WriteHeader(http.StatusNoContent)
+				}
+			})
+
+			code := cmdSend(context.Background(), append(tt.args, "-input", input))
+			if code != tt.wantCode {
+				t.Errorf("cmdSend = %d, want %d", code, tt.wantCode)
+			}
+			sort.Strings(requests)
+			if got, want := strings.Join(requests, " "), strings.Join(tt.want, " "); got != want {
+				t.Errorf("requests = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCmdSendRejectsMalformedInput(t *testing.T) {
+	inTempDir(t)
+	t.Setenv("SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T/B/X")
+	routeDefaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request sent for malformed input: %s", r.URL)
+	})
+
+	bad := []Article{{Title: "no uuid"}}
+	data, _ := json.Marshal(bad)
+	path := filepath.Join(t.TempDir(), "bad.json")
+	writeFile(t, path, string(data))
+	if code := cmdSend(context.Background(), []string{"-slack", "-input", path}); code != 1 {
+		t.Errorf("cmdSend with malformed input = %d, want 1", code)
+	}
+}
+
+func TestTimezoneFlag(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantPosted string
+		wantErr    string
+	}{
+		{"default IST", nil, "Posted: 2024-05-01 15:30:00 IST", ""},
+		{"empty is IST", []string{"-tz", ""}, "Posted: 2024-05-01 15:30:00 IST", ""},
+		{"IANA zone", []string{"-tz", "America/New_York"}, "Posted: 2024-05-01 06:00:00 EDT", ""},
+		{"UTC", []string{"-tz", "UTC"}, "Posted: 2024-05-01 10:00:00 UTC", ""},
+		{"unknown zone", []string{"-tz", "Mars/Olympus_Mons"}, "", `unknown time zone "Mars/Olympus_Mons"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			cfg, err := loadCommandConfig("test", tt.args, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadCommandConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filename := filepath.Join("fetched_articles", "digest.txt")
+			if err := os.MkdirAll("fetched_articles", 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := writeArticlesToFile([]Article{testArticle("u1", "2024-05-01T10:00:00Z")}, filename, cfg); err != nil {
+				t.Fatal(err)
+			}
+			if content := readFile(t, filename); !strings.Contains(content, tt.wantPosted+"\n") {
+				t.Errorf("file lacks %q:\n%s", tt.wantPosted, content)
+			}
+		})
+	}
+}
+
+func TestDigestDryRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantRequests int
+		wantWrites   bool
+	}{
+		{"normal run", nil, 1, true},
+		{"dry run", []string{"-dry-run"}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			article := testArticle("u1", time.Now().Add(-time.Hour).UTC().Format(time.RFC3339))
+			data, err := json.Marshal([]Article{article})
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeFile(t, "articles.json", string(data))
+			for key, value := range map[string]string{
+				"SOURCE": "file:articles.json", "STATE_FILE": "state.json",
+				"FROM_EMAIL": "bot@example.com", "RECIPIENTS": "a@example.com", "SENDGRID_API_KEY": "key",
+			} {
+				t.Setenv(key, value)
+			}
+			var requests int
+			routeDefaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(http.StatusAccepted)
+			})
+
+			var code int
+			out := captureStdout(t, func() { code = cmdDigest(context.Background(), tt.args) })
+			if code != 0 {
+				t.Fatalf("cmdDigest = %d, want 0", code)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("email requests = %d, want %d", requests, tt.wantRequests)
+			}
+			for _, path := range []string{"state.json", "fetched_articles"} {
+				if _, err := os.Stat(path); (err == nil) != tt.wantWrites {
+					t.Errorf("%s exists = %v, want %v", path, err == nil, tt.wantWrites)
+				}
+			}
+			if got := strings.Contains(out, "Dry run:"); got != (tt.name == "dry run") {
+				t.Errorf("dry-run notice printed = %v in:\n%s", got, out)
+			}
+			if !strings.Contains(out, article.Title) {
+				t.Errorf("output lacks the article title:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestSinceFlag(t *testing.T) {
+	inTempDir(t)
+	now := time.Now()
+	tests := []struct {
+		name    string
+		since   string
+		want    time.Time
+		wantErr string
+	}{
+		{"RFC3339", "2024-05-01T10:00:00Z", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), ""},
+		{"hours", "72h", now.Add(-72 * time.Hour), ""},
+		{"days", "3d", now.Add(-72 * time.Hour), ""},
+		{"garbage", "last tuesday", time.Time{}, "must be an RFC3339 time"},
+		{"negative", "-5h", time.Time{}, "must be an RFC3339 time"},
+		{"future", now.Add(time.Hour).UTC().Format(time.RFC3339), time.Time{}, "is in the future"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadCommandConfig("test", []string{"-since", tt.since}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadCommandConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Relative values are taken from the time the flag is parsed
+			if diff := cfg.Since.Sub(tt.want); diff < 0 || diff > time.Minute {
+				t.Errorf("Since = %v, want %v", cfg.Since, tt.want)
+			}
+		})
+	}
+
+	// With -dry-run, -since reaches past the saved cutoff without touching the state
+	t.Setenv("SOURCE", "file:articles.json")
+	t.Setenv("STATE_FILE", "state.json")
+	article := testArticle("u1", now.Add(-5*time.Hour).UTC().Format(time.RFC3339))
+	data, err := json.Marshal([]Article{article})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, "articles.json", string(data))
+	if err := writeState("state.json", State{LastProcessed: now.Add(-time.Hour).UTC().Truncate(time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	before := readFile(t, "state.json")
+
+	var code int
+	out := captureStdout(t, func() { code = cmdDigest(context.Background(), []string{"-since", "72h", "-dry-run"}) })
+	if code != 0 {
+		t.Fatalf("cmdDigest = %d, want 0", code)
+	}
+	if !strings.Contains(out, "Using -since instead of the state file") || !strings.Contains(out, article.Title) {
+		t.Errorf("run did not use -since to report the older article:\n%s", out)
+	}
+	if after := readFile(t, "state.json"); after != before {
+		t.Errorf("dry run changed the state file:\n%s\nwant\n%s", after, before)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// templatePattern matches the names renderFilename produces from template for
+// text files: time layouts and {count} match any run of characters, {label}
+// only the given label
+func templatePattern(template, label string) *regexp.Regexp {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, loc := range templatePlaceholder.FindAllStringSubmatchIndex(template, -1) {
+		pattern.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		switch template[loc[2]:loc[3]] {
+		case "ext":
+			pattern.WriteString(regexp.QuoteMeta(formatExtension(formatText)))
+		case "label":
+			pattern.WriteString(regexp.QuoteMeta(label))
+		case "count":
+			pattern.WriteString(`\d+`)
+		default:
+			pattern.WriteString(`[^/]+?`)
+		}
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String())
+}
+
+// compressOldArchives gzips the text files in dir named by the filename template
+// and last modified before olderThan ago, removing the originals. The compressed
+// files keep the originals' modification time. It returns how many were compressed.
+func compressOldArchives(dir, template, label string, olderThan time.Duration, now time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	pattern := templatePattern(template, label)
+	cutoff := now.Add(-olderThan)
+	compressed := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), ".txt") || !pattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return compressed, fmt.Errorf("failed to stat %s: %w", entry.Name(), err)
+		}
+		if !info.ModTime().Before(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(path + ".gz"); err == nil {
+			fmt.Fprintf(os.Stderr, "Warning: Not compressing %s: %s.gz already exists\n", path, path)
+			continue
+		}
+		if err := gzipFile(path, info.ModTime()); err != nil {
+			return compressed, err
+		}
+		compressed++
+	}
+	return compressed, nil
+}
+
+// gzipFile replaces path with path.gz, written through a temporary file so an
+// interrupted run never leaves a truncated archive, with its mtime set to modTime
+func gzipFile(path string, modTime time.Time) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer src.Close()
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".gz.tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmpName)
+		}
+	}()
+
+	zw := gzip.NewWriter(tmp)
+	zw.Name = filepath.Base(path)
+	zw.ModTime = modTime
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, 0644)
+	}
+	if err == nil {
+		err = os.Chtimes(tmpName, modTime, modTime)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, path+".gz")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove %s after compressing it: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCompressOldArchives(t *testing.T) {
+	now := time.Date(2024, 5, 20, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	files := []struct {
+		name           string
+		age            time.Duration
+		wantCompressed bool
+	}{
+		{"leetcode_articles_2024-05-01_10-00-00.txt", 19 * 24 * time.Hour, true},
+		{"leetcode_articles_2024-05-19_10-00-00.txt", 26 * time.Hour, false},
+		{"leetcode_articles_2024-05-02_10-00-00.json", 18 * 24 * time.Hour, false},
+		{"notes.txt", 30 * 24 * time.Hour, false},
+		{"leetcode_articles_2024-05-03_10-00-00.txt", 17 * 24 * time.Hour, false}, // its .gz already exists
+	}
+	writeFile(t, filepath.Join(dir, files[4].name+".gz"), "existing")
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		writeFile(t, path, "content of "+f.name)
+		if err := os.Chtimes(path, now.Add(-f.age), now.Add(-f.age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := compressOldArchives(dir, defaultFilenameTemplate, "", 7*24*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("compressed %d files, want 1", n)
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		_, origErr := os.Stat(path)
+		if f.wantCompressed != os.IsNotExist(origErr) {
+			t.Errorf("%s: original removed = %v, want %v", f.name, os.IsNotExist(origErr), f.wantCompressed)
+		}
+		if !f.wantCompressed {
+			continue
+		}
+		data, err := readMaybeGzipped(path + ".gz")
+		if err != nil {
+			t.Fatalf("%s: %v", f.name, err)
+		}
+		if string(data) != "content of "+f.name {
+			t.Errorf("%s.gz holds %q", f.name, data)
+		}
+		info, err := os.Stat(path + ".gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(now.Add(-f.age)) {
+			t.Errorf("%s.gz modified %v, want the original's %v", f.name, info.ModTime(), now.Add(-f.age))
+		}
+	}
+	if got := readFile(t, filepath.Join(dir, files[4].name+".gz")); got != "existing" {
+		t.Errorf("existing archive overwritten with %q", got)
+	}
+}
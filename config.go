@@ -0,0 +1,668 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the runtime settings read from environment variables
+type Config struct {
+	EmailProvider      string // providerSendGrid, providerSMTP or providerMailgun
+	SendGridAPIKey     string
+	SendGridBatchSize  int    // recipients per SendGrid request, at most sendGridMaxRecipients
+	SendGridTemplateID string // stored dynamic template to render instead of the built-in HTML
+	SMTP               SMTPConfig
+	MailgunDomain      string
+	MailgunAPIKey      string
+	SlackWebhookURL    string
+	PostToSlack        bool          // post the digest to SlackWebhookURL, set by -slack
+	SlackFormat        articleFormat // SLACK_LAYOUT, SLACK_SUMMARY_LENGTH and SLACK_SECTIONS
+	DiscordWebhookURL  string
+	PostToDiscord      bool          // post the digest to DiscordWebhookURL, set by -discord
+	DiscordFormat      articleFormat // DISCORD_LAYOUT, DISCORD_SUMMARY_LENGTH and DISCORD_SECTIONS
+	ReadLaterService   string        // readLaterPocket or readLaterInstapaper to save each article there; "" disables it
+	PocketConsumerKey  string
+	PocketAccessToken  string
+	InstapaperUsername string
+	InstapaperPassword string
+	FromEmail          string
+	FromName           string
+	ToEmails           []string
+	EnableFileOutput   bool
+	Source             string        // "leetcode" (default) or "file:<path>"
+	RunRetries         int           // times a failed fetch is repeated from scratch
+	MaxAge             time.Duration // never fetch articles older than this; 0 means no limit
+	ChunkWindow        time.Duration // deliver the fetched range in windows of this length; 0 delivers it at once
+	AlwaysShowLatest   int           // fill the digest up to N with the latest articles, new or not; ignored with ChunkWindow
+	MaxRuntime         time.Duration // stop fetching in time to deliver within this; 0 means no limit
+	MinRunInterval     time.Duration // skip runs started sooner than this after the last one; 0 means no limit
+	CompressOlderThan  time.Duration // gzip text files in fetched_articles older than this after a run; 0 disables it
+	Force              bool          // run even within MinRunInterval
+	Since              time.Time     // cutoff set by -since, used instead of the state file's; zero means unset
+	TitleMaxLen        int           // 0 means unlimited
+	StateFile          string        // where the last processed state is kept
+	DedupKey           string        // dedupUUID, dedupTopicID or dedupSlug: the identity recorded in the state
+	ArchiveIndex       string        // file of archived UUIDs used to skip repeats; empty disables it
+	StatsJSONL         string        // file each run appends a JSON stats line to; empty disables it
+	WeeklyReport       bool          // email weekly stats on WeeklyReportDay, set by WEEKLY_REPORT_DAY
+	WeeklyReportDay    time.Weekday
+	WeeklyReportFile   string // collects the articles the weekly stats are computed from
+	FilenameTemplate   string // see renderFilename
+	RunLabel           string // names the run (e.g. "morning") in filenames and headers
+	DailyFile          bool   // append each day's runs to a single dated file
+	AtomicWrite        bool   // write output through a synced temp file and rename
+	VerifyWrite        bool   // re-read output files and check they are complete
+	WriteEmptyFile     bool   // write a header-only file when there are no articles
+	TouchOnEmpty       bool   // write a last_run_empty_<date>.txt marker when there are no articles
+	OutputFormat       string // formatText, formatJSON, formatMarkdown or formatRSS
+	SeparatorWidth     int    // width of the separator lines in the text file
+	FileGroupBy        string // "" or groupByDay to add date headings to the text file
+	FileIncludeURLList bool   // end the text file with a plain list of article URLs
+	UserAgent          string // sent with every LeetCode request
+	Referer            string
+	Origin             string
+	SessionCookie      string
+	LightQuery         bool // scan with a trimmed query, see clientOptions
+	LightRefetch       bool
+	PageDelay          time.Duration   // pause between page requests
+	MaxPages           int             // cap on pages requested per scan; 0 means no limit
+	FetchWorkers       int             // pages requested at once after the first, at most maxFetchWorkers
+	DisabledFields     map[string]bool // optional query fields left out, see optionalQueryFields
+	MaxRPS             float64         // requests per second to LeetCode; 0 means unlimited
+	HTTPClient         Doer            // sends LeetCode, email and chat requests; nil means defaultHTTPClient
+	EmailSender        EmailSender     // sends the emails; nil means the sender for EmailProvider
+	PersistedQueryHash string          // sha256 hash sent in place of the listing query, see persistedQuery
+	TagSlugs           []string        // restrict the fetch to these tags, set by -tags
+	Keywords           []string        // search terms the fetch must match, set by -keywords
+	OrderBy            string          // orderMostRecent, orderMostVotes or orderHottest
+	FetchRetries       int             // retries of a transiently failed page request
+	FetchRetryDelay    time.Duration   // wait before the first of those retries
+	FullContent        bool            // fetch the full body of reported articles, set by -full-content
+
+	Verbose bool // show tag and reaction counts in the console list
+	DryRun  bool // fetch and list articles without emailing, writing files or saving state
+
+	PostRunHook      string // shell command run after a successful run
+	PostRunHookFatal bool   // fail the run when the hook fails
+
+	// Email rendering
+	ShowThumbnails bool
+	EmailFormat    articleFormat // EMAIL_LAYOUT, EMAIL_SUMMARY_LENGTH and EMAIL_SECTIONS (or CARD_LAYOUT)
+	AutoCompact    bool          // resend with layoutCompact when the provider rejects the email as too large
+	FeatureTopN    int           // render the N most reacted articles in full, the rest compact
+	DateFormat     dateFormat
+	Location       *time.Location  // time zone of displayed dates, IST unless set with -tz
+	ReactionFormat string          // {type} and {count} placeholders, used in email and file
+	HTMLStripMode  string          // stripNone (default), stripBasic or stripAggressive, applied to summaries
+	MaxTagsShown   int             // 0 shows all tags
+	FeaturedTags   map[string]bool // tag slugs whose chips are highlighted
+
+	ShowDiscussionCTA bool // add a "Join the discussion" link to each card
+	MaxEmailArticles  int  // render at most N articles and attach the full list; 0 renders all
+
+	// TrackingPixelURL adds a 1x1 image loaded from this URL, with the run ID as
+	// a query parameter, for open tracking. Off by default: every recipient's
+	// mail client then contacts that server when the email is opened, revealing
+	// the time of opening and their IP address to whoever runs it.
+	TrackingPixelURL string
+
+	AnonymizeAuthors bool   // replace author names with stable pseudonyms
+	AnonymizeSalt    string // keeps pseudonyms from being reversed by hashing known names
+
+	// Filters
+	RequireReactionTypes []string // keep only articles with one of these reaction types
+	MaxPerAuthor         int      // keep at most N articles per author; 0 means no limit
+	SortBy               string   // "" keeps the fetched order; sortReactions ranks by total reactions
+	MinReactions         int      // keep only articles with at least this many reactions; 0 keeps all
+	MaxArticles          int      // report only the N most recent articles after filtering; 0 means no limit
+
+	// MatchedArticles is set while delivering when MaxArticles trimmed the digest:
+	// how many articles there were before, for the "(showing N of M)" notes
+	MatchedArticles int
+}
+
+// Email providers
+const (
+	providerSendGrid = "sendgrid"
+	providerSMTP     = "smtp"
+	providerMailgun  = "mailgun"
+)
+
+// providerCredentials names the settings holding each provider's credentials
+var providerCredentials = map[string]string{
+	providerSendGrid: "SENDGRID_API_KEY",
+	providerSMTP:     "SMTP_USER and SMTP_PASS",
+	providerMailgun:  "MAILGUN_DOMAIN and MAILGUN_API_KEY",
+}
+
+// parseEmailProvider validates an email provider name
+func parseEmailProvider(v string) (string, error) {
+	switch p := strings.ToLower(strings.TrimSpace(v)); p {
+	case providerSendGrid, providerSMTP, providerMailgun:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown provider %q: must be %s, %s or %s", v, providerSendGrid, providerSMTP, providerMailgun)
+	}
+}
+
+// Sender returns the configured EmailSender, or the one for the configured provider
+func (c Config) Sender() EmailSender {
+	if c.EmailSender != nil {
+		return c.EmailSender
+	}
+	switch c.EmailProvider {
+	case providerSMTP:
+		return SMTPSender{Config: c.SMTP}
+	case providerMailgun:
+		return MailgunSender{Domain: c.MailgunDomain, APIKey: c.MailgunAPIKey, Client: c.HTTPClient}
+	default:
+		return SendGridSender{APIKey: c.SendGridAPIKey, BatchSize: c.SendGridBatchSize, Client: c.HTTPClient}
+	}
+}
+
+// Notifiers returns the channels the digest is posted to besides email. saved
+// holds the URLs already sent to the read-later service; it may be nil when
+// the notifiers are only listed.
+func (c Config) Notifiers(saved *savedURLs) []Notifier {
+	var notifiers []Notifier
+	if c.PostToSlack {
+		notifiers = append(notifiers, SlackNotifier{WebhookURL: c.SlackWebhookURL, Format: c.SlackFormat, Client: c.HTTPClient})
+	}
+	if c.PostToDiscord {
+		notifiers = append(notifiers, DiscordNotifier{WebhookURL: c.DiscordWebhookURL, Format: c.DiscordFormat, Client: c.HTTPClient})
+	}
+	if c.ReadLaterService != "" {
+		notifiers = append(notifiers, ReadLaterNotifier{
+			Service:            c.ReadLaterService,
+			PocketConsumerKey:  c.PocketConsumerKey,
+			PocketAccessToken:  c.PocketAccessToken,
+			InstapaperUsername: c.InstapaperUsername,
+			InstapaperPassword: c.InstapaperPassword,
+			Saved:              saved,
+			Client:             c.HTTPClient,
+		})
+	}
+	return notifiers
+}
+
+// EmailEnabled reports whether enough settings are present to send email
+func (c Config) EmailEnabled() bool {
+	if c.FromEmail == "" || len(c.ToEmails) == 0 {
+		return false
+	}
+	switch c.EmailProvider {
+	case providerSMTP:
+		return c.SMTP.Host != ""
+	case providerMailgun:
+		return c.MailgunDomain != "" && c.MailgunAPIKey != ""
+	default:
+		return c.SendGridAPIKey != ""
+	}
+}
+
+// ClientOptions returns the settings used for requests to LeetCode. Each call
+// creates a new rate limiter, so a run should use one set of options throughout.
+func (c Config) ClientOptions() clientOptions {
+	return clientOptions{
+		UserAgent:     c.UserAgent,
+		Referer:       c.Referer,
+		Origin:        c.Origin,
+		SessionCookie: c.SessionCookie,
+
+		LightQuery:         c.LightQuery,
+		RefetchFullDetails: c.LightRefetch,
+		PageDelay:          c.PageDelay,
+		MaxPages:           c.MaxPages,
+		Workers:            c.FetchWorkers,
+		DisabledFields:     c.DisabledFields,
+		Limiter:            newRateLimiter(c.MaxRPS),
+		HTTPClient:         c.HTTPClient,
+		PersistedQuery:     c.persistedQuery(),
+		TagSlugs:           c.TagSlugs,
+		Keywords:           c.Keywords,
+		OrderBy:            c.OrderBy,
+		MaxRetries:         c.FetchRetries,
+		RetryBaseDelay:     c.FetchRetryDelay,
+	}
+}
+
+// persistedQuery returns the PERSISTED_QUERY_HASH to send, or nil when unset
+func (c Config) persistedQuery() *persistedQuery {
+	if c.PersistedQueryHash == "" {
+		return nil
+	}
+	return &persistedQuery{Hash: c.PersistedQueryHash}
+}
+
+// loadConfig reads and validates configuration from environment variables
+func loadConfig() (Config, error) {
+	cfg := Config{
+		SendGridAPIKey:     strings.TrimSpace(os.Getenv("SENDGRID_API_KEY")),
+		MailgunDomain:      strings.TrimSpace(os.Getenv("MAILGUN_DOMAIN")),
+		MailgunAPIKey:      strings.TrimSpace(os.Getenv("MAILGUN_API_KEY")),
+		SlackWebhookURL:    strings.TrimSpace(os.Getenv("SLACK_WEBHOOK_URL")),
+		PersistedQueryHash: strings.ToLower(strings.TrimSpace(os.Getenv("PERSISTED_QUERY_HASH"))),
+		PocketConsumerKey:  strings.TrimSpace(os.Getenv("POCKET_CONSUMER_KEY")),
+		PocketAccessToken:  strings.TrimSpace(os.Getenv("POCKET_ACCESS_TOKEN")),
+		InstapaperUsername: strings.TrimSpace(os.Getenv("INSTAPAPER_USERNAME")),
+		InstapaperPassword: os.Getenv("INSTAPAPER_PASSWORD"),
+		DiscordWebhookURL:  strings.TrimSpace(os.Getenv("DISCORD_WEBHOOK_URL")),
+		SendGridTemplateID: strings.TrimSpace(os.Getenv("SENDGRID_TEMPLATE_ID")),
+		SMTP: SMTPConfig{
+			Host:     strings.TrimSpace(os.Getenv("SMTP_HOST")),
+			Port:     envString("SMTP_PORT", "587"),
+			Username: strings.TrimSpace(os.Getenv("SMTP_USER")),
+			Password: os.Getenv("SMTP_PASS"),
+		},
+		FromEmail:        strings.TrimSpace(os.Getenv("FROM_EMAIL")),
+		FromName:         strings.TrimSpace(os.Getenv("FROM_NAME")),
+		EnableFileOutput: os.Getenv("ENABLE_FILE_OUTPUT") != "false", // Default to true
+		Source:           strings.TrimSpace(os.Getenv("SOURCE")),
+		StateFile:        envString("STATE_FILE", defaultStateFile),
+		DailyFile:        os.Getenv("DAILY_FILE") == "true",
+		AtomicWrite:      os.Getenv("ATOMIC_WRITE") != "false", // Default to true
+		WriteEmptyFile:   os.Getenv("WRITE_EMPTY_FILE") == "true",
+		TouchOnEmpty:     os.Getenv("TOUCH_ON_EMPTY") == "true",
+		VerifyWrite:      os.Getenv("VERIFY_WRITE") == "true",
+		RunLabel:         strings.TrimSpace(os.Getenv("RUN_LABEL")),
+		UserAgent:        envString("USER_AGENT", defaultUserAgent),
+		Referer:          envString("LEETCODE_REFERER", defaultReferer),
+		Origin:           envString("LEETCODE_ORIGIN", defaultOrigin),
+		SessionCookie:    strings.TrimSpace(os.Getenv("LEETCODE_SESSION")),
+		LightQuery:       os.Getenv("LIGHT_QUERY") == "true",
+		LightRefetch:     os.Getenv("LIGHT_QUERY_REFETCH") != "false", // Default to true
+
+		PostRunHook:      strings.TrimSpace(os.Getenv("POST_RUN_HOOK")),
+		PostRunHookFatal: os.Getenv("POST_RUN_HOOK_FATAL") == "true",
+
+		ShowThumbnails: os.Getenv("SHOW_THUMBNAILS") == "true",
+		AutoCompact:    os.Getenv("AUTO_COMPACT_ON_OVERSIZE") == "true",
+		DateFormat:     dateFormatForLocale(strings.TrimSpace(os.Getenv("LOCALE"))),
+		Location:       defaultLocation,
+		ReactionFormat: envString("REACTION_FORMAT", defaultReactionFormat),
+
+		ShowDiscussionCTA: os.Getenv("SHOW_DISCUSSION_CTA") == "true",
+		TrackingPixelURL:  strings.TrimSpace(os.Getenv("TRACKING_PIXEL_URL")),
+		FeaturedTags:      make(map[string]bool),
+
+		FileGroupBy:        strings.ToLower(strings.TrimSpace(os.Getenv("FILE_GROUP_BY"))),
+		FileIncludeURLList: os.Getenv("FILE_INCLUDE_URL_LIST") == "true",
+
+		ArchiveIndex: strings.TrimSpace(os.Getenv("ARCHIVE_INDEX")),
+		StatsJSONL:   strings.TrimSpace(os.Getenv("STATS_JSONL")),
+		OrderBy:      strings.ToUpper(envString("ORDER_BY", orderMostRecent)),
+
+		AnonymizeAuthors: os.Getenv("ANONYMIZE_AUTHORS") == "true",
+		AnonymizeSalt:    os.Getenv("ANONYMIZE_SALT"),
+
+		RequireReactionTypes: envList("REQUIRE_REACTION_TYPES"),
+	}
+
+	for _, slug := range envList("FEATURED_TAGS") {
+		cfg.FeaturedTags[strings.ToLower(slug)] = true
+	}
+
+	defaultTemplate := defaultFilenameTemplate
+	if cfg.DailyFile {
+		defaultTemplate = dailyFilenameTemplate
+	}
+	cfg.FilenameTemplate = envString("FILENAME_TEMPLATE", defaultTemplate)
+
+	var err error
+	if cfg.PageDelay, err = envDuration("PAGE_DELAY", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.DisabledFields, err = parseDisabledFields(envList("DISABLE_FIELDS")); err != nil {
+		return Config{}, fmt.Errorf("invalid DISABLE_FIELDS: %w", err)
+	}
+	if h := cfg.PersistedQueryHash; h != "" && (len(h) != 64 || strings.Trim(h, "0123456789abcdef") != "") {
+		return Config{}, fmt.Errorf("invalid PERSISTED_QUERY_HASH %q: must be a SHA-256 hash of 64 hex digits", h)
+	}
+	if cfg.MaxRPS, err = envFloat("MAX_RPS"); err != nil {
+		return Config{}, err
+	}
+	switch cfg.OrderBy {
+	case orderMostRecent, orderMostVotes, orderHottest:
+	default:
+		return Config{}, fmt.Errorf("invalid ORDER_BY %q: must be %s, %s or %s", cfg.OrderBy, orderMostRecent, orderMostVotes, orderHottest)
+	}
+	if cfg.HTMLStripMode, err = parseHTMLStripMode(os.Getenv("HTML_STRIP_MODE")); err != nil {
+		return Config{}, fmt.Errorf("invalid HTML_STRIP_MODE: %w", err)
+	}
+	if cfg.SortBy, err = parseSortBy(os.Getenv("SORT_BY")); err != nil {
+		return Config{}, fmt.Errorf("invalid SORT_BY: %w", err)
+	}
+	if cfg.MinReactions, err = envCount("MIN_REACTIONS"); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxArticles, err = envCount("MAX_ARTICLES"); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxPages, err = envCount("MAX_PAGES"); err != nil {
+		return Config{}, err
+	}
+	if cfg.FetchWorkers, err = envInt("FETCH_WORKERS", defaultFetchWorkers); err != nil {
+		return Config{}, err
+	}
+	if cfg.FetchWorkers < 1 || cfg.FetchWorkers > maxFetchWorkers {
+		return Config{}, fmt.Errorf("FETCH_WORKERS must be between 1 and %d", maxFetchWorkers)
+	}
+	if cfg.FetchRetries, err = envInt("FETCH_RETRIES", 3); err != nil {
+		return Config{}, err
+	}
+	if cfg.FetchRetries < 0 {
+		return Config{}, fmt.Errorf("FETCH_RETRIES must not be negative")
+	}
+	if cfg.FetchRetryDelay, err = envDuration("FETCH_RETRY_DELAY", time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxAge, err = envDuration("MAX_AGE", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.ChunkWindow, err = envDuration("CHUNK_WINDOW", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxRuntime, err = envDuration("MAX_RUNTIME", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.MinRunInterval, err = envDuration("MIN_RUN_INTERVAL", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.CompressOlderThan, err = envDuration("COMPRESS_OLDER_THAN", 0); err != nil {
+		return Config{}, err
+	}
+	if day := os.Getenv("WEEKLY_REPORT_DAY"); day != "" {
+		if cfg.WeeklyReportDay, err = parseWeekday(day); err != nil {
+			return Config{}, fmt.Errorf("invalid WEEKLY_REPORT_DAY: %w", err)
+		}
+		cfg.WeeklyReport = true
+		cfg.WeeklyReportFile = envString("WEEKLY_REPORT_FILE", defaultWeeklyReportFile)
+	}
+	if cfg.ToEmails, err = parseRecipients(recipientList()); err != nil {
+		return Config{}, fmt.Errorf("invalid RECIPIENTS: %w", err)
+	}
+	if cfg.FromEmail != "" && len(cfg.ToEmails) == 0 {
+		return Config{}, fmt.Errorf("FROM_EMAIL is set but RECIPIENTS is empty; list the addresses to email, separated by commas")
+	}
+	if cfg.DedupKey, err = parseDedupKey(envString("DEDUP_KEY", dedupUUID)); err != nil {
+		return Config{}, fmt.Errorf("invalid DEDUP_KEY: %w", err)
+	}
+	if cfg.OutputFormat, err = parseOutputFormat(envString("OUTPUT_FORMAT", formatText)); err != nil {
+		return Config{}, fmt.Errorf("invalid OUTPUT_FORMAT: %w", err)
+	}
+	if cfg.MaxPerAuthor, err = envCount("MAX_PER_AUTHOR"); err != nil {
+		return Config{}, err
+	}
+	if cfg.RunRetries, err = envCount("RUN_RETRIES"); err != nil {
+		return Config{}, err
+	}
+	if cfg.TitleMaxLen, err = envCount("TITLE_MAX_LEN"); err != nil {
+		return Config{}, err
+	}
+	if cfg.AlwaysShowLatest, err = envCount("ALWAYS_SHOW_LATEST"); err != nil {
+		return Config{}, err
+	}
+	if cfg.SeparatorWidth, err = envInt("SEPARATOR_WIDTH", 80); err != nil {
+		return Config{}, err
+	}
+	if cfg.SeparatorWidth < 20 || cfg.SeparatorWidth > 200 {
+		return Config{}, fmt.Errorf("SEPARATOR_WIDTH must be between 20 and 200, got %d", cfg.SeparatorWidth)
+	}
+	if cfg.EmailProvider, err = parseEmailProvider(envString("EMAIL_PROVIDER", providerSendGrid)); err != nil {
+		return Config{}, fmt.Errorf("invalid EMAIL_PROVIDER: %w", err)
+	}
+	if cfg.SendGridBatchSize, err = envInt("SENDGRID_BATCH_SIZE", sendGridMaxRecipients); err != nil {
+		return Config{}, err
+	}
+	if cfg.SendGridBatchSize < 1 || cfg.SendGridBatchSize > sendGridMaxRecipients {
+		return Config{}, fmt.Errorf("SENDGRID_BATCH_SIZE must be between 1 and %d", sendGridMaxRecipients)
+	}
+	if cfg.FileGroupBy != "" && cfg.FileGroupBy != groupByDay {
+		return Config{}, fmt.Errorf("invalid FILE_GROUP_BY %q: must be %s or empty", cfg.FileGroupBy, groupByDay)
+	}
+	if cfg.TrackingPixelURL != "" {
+		if u, err := url.Parse(cfg.TrackingPixelURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid TRACKING_PIXEL_URL %q: must be an http or https URL", cfg.TrackingPixelURL)
+		}
+	}
+	cardLayout, err := parseCardLayout(envList("CARD_LAYOUT"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid CARD_LAYOUT: %w", err)
+	}
+	emailFormat := articleFormat{Layout: layoutFull, SummaryLen: defaultSummaryLen, Sections: cardLayout}
+	if cfg.EmailFormat, err = loadArticleFormat("EMAIL", emailFormat); err != nil {
+		return Config{}, err
+	}
+	// Chat messages show only the summary unless configured otherwise
+	chatFormat := articleFormat{Layout: layoutFull, SummaryLen: defaultSummaryLen, Sections: []string{"summary"}}
+	if cfg.SlackFormat, err = loadArticleFormat("SLACK", chatFormat); err != nil {
+		return Config{}, err
+	}
+	if cfg.DiscordFormat, err = loadArticleFormat("DISCORD", chatFormat); err != nil {
+		return Config{}, err
+	}
+	if cfg.ReadLaterService, err = parseReadLaterService(os.Getenv("READ_LATER_SERVICE")); err != nil {
+		return Config{}, fmt.Errorf("invalid READ_LATER_SERVICE: %w", err)
+	}
+	switch {
+	case cfg.ReadLaterService == readLaterPocket && (cfg.PocketConsumerKey == "" || cfg.PocketAccessToken == ""):
+		return Config{}, fmt.Errorf("READ_LATER_SERVICE=pocket requires POCKET_CONSUMER_KEY and POCKET_ACCESS_TOKEN")
+	case cfg.ReadLaterService == readLaterInstapaper && cfg.InstapaperUsername == "":
+		return Config{}, fmt.Errorf("READ_LATER_SERVICE=instapaper requires INSTAPAPER_USERNAME")
+	}
+	if !strings.Contains(cfg.ReactionFormat, "{type}") && !strings.Contains(cfg.ReactionFormat, "{count}") {
+		return Config{}, fmt.Errorf("invalid REACTION_FORMAT %q: must contain {type} or {count}", cfg.ReactionFormat)
+	}
+	if cfg.MaxTagsShown, err = envCount("MAX_TAGS_SHOWN"); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxEmailArticles, err = envCount("MAX_EMAIL_ARTICLES"); err != nil {
+		return Config{}, err
+	}
+	if cfg.FeatureTopN, err = envCount("FEATURE_TOP_N"); err != nil {
+		return Config{}, err
+	}
+
+	if cfg.RunLabel != "" {
+		cfg.FilenameTemplate = labelledTemplate(cfg.FilenameTemplate)
+	}
+	if _, err := renderFilename(cfg.FilenameTemplate, time.Now(), 0, "txt", cfg.RunLabel); err != nil {
+		return Config{}, fmt.Errorf("invalid FILENAME_TEMPLATE: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// bindFlags registers command-line flags that override the environment settings
+func (c *Config) bindFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Verbose, "verbose", c.Verbose, "show tag and reaction counts in the console list")
+	fs.Func("format", "output file format: txt, json, markdown or rss (default from OUTPUT_FORMAT)", func(v string) error {
+		format, err := parseOutputFormat(v)
+		c.OutputFormat = format
+		return err
+	})
+	fs.Func("provider", "email provider: sendgrid, smtp or mailgun (default from EMAIL_PROVIDER)", func(v string) error {
+		provider, err := parseEmailProvider(v)
+		c.EmailProvider = provider
+		return err
+	})
+	fs.Func("tz", "IANA time zone for dates in the email and files, such as America/New_York (default IST)", func(v string) error {
+		if v == "" {
+			c.Location = defaultLocation
+			return nil
+		}
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			return fmt.Errorf("unknown time zone %q; use an IANA name such as Asia/Kolkata", v)
+		}
+		c.Location = loc
+		return nil
+	})
+	fs.Func("since", "fetch articles published after this RFC3339 time, or this long before now (e.g. 72h or 3d), ignoring the state file", func(v string) error {
+		since, err := parseSince(v, time.Now())
+		if err != nil {
+			return err
+		}
+		c.Since = since
+		return nil
+	})
+	fs.Func("tags", "only fetch articles with these comma-separated tag slugs", func(v string) error {
+		c.TagSlugs = parseTagSlugs(v)
+		return nil
+	})
+	fs.BoolVar(&c.FullContent, "full-content", c.FullContent, "fetch the full body of each reported article (one extra request per article)")
+	fs.Func("sort", "order of the digest: recent or reactions, applied after fetching (default from SORT_BY)", func(v string) error {
+		sortBy, err := parseSortBy(v)
+		c.SortBy = sortBy
+		return err
+	})
+	fs.Func("min-reactions", "only keep articles with at least N reactions in total (default from MIN_REACTIONS)", func(v string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return fmt.Errorf("must be a non-negative integer")
+		}
+		c.MinReactions = n
+		return nil
+	})
+	fs.Func("max-articles", "report only the N most recent articles after filtering (default from MAX_ARTICLES, 0 for no limit)", func(v string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return fmt.Errorf("must be a non-negative integer")
+		}
+		c.MaxArticles = n
+		return nil
+	})
+	fs.Func("keywords", "only fetch articles matching these space- or comma-separated keywords", func(v string) error {
+		c.Keywords = parseKeywords(v)
+		return nil
+	})
+}
+
+// envList reads a comma-separated environment variable, dropping empty entries
+func envList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// recipientList reads RECIPIENTS, or TO_EMAILS as it was called before
+func recipientList() []string {
+	if recipients := envList("RECIPIENTS"); len(recipients) > 0 {
+		return recipients
+	}
+	return envList("TO_EMAILS")
+}
+
+// parseRecipients checks that every entry is a valid email address, returning
+// the bare addresses. All malformed entries are listed in the error.
+func parseRecipients(list []string) ([]string, error) {
+	var emails, invalid []string
+	for _, entry := range list {
+		addr, err := mail.ParseAddress(entry)
+		if err != nil {
+			invalid = append(invalid, strconv.Quote(entry))
+			continue
+		}
+		emails = append(emails, addr.Address)
+	}
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("malformed email addresses: %s", strings.Join(invalid, ", "))
+	}
+	return emails, nil
+}
+
+// envString reads a trimmed environment variable, returning def when unset
+func envString(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+// envCount reads a non-negative integer environment variable, where 0 (the default) means unset
+func envCount(key string) (int, error) {
+	n, err := envInt(key, 0)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%s must not be negative", key)
+	}
+	return n, nil
+}
+
+// envInt reads an integer environment variable, returning def when unset
+func envInt(key string, def int) (int, error) {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: must be an integer", key, v)
+	}
+	return n, nil
+}
+
+// envFloat reads a non-negative number, where 0 (the default) means unset
+func envFloat(key string) (float64, error) {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative number", key, v)
+	}
+	return f, nil
+}
+
+// parseSince parses a -since value: an RFC3339 time, or a duration such as 72h or
+// 3d that is taken as that long before now
+func parseSince(v string, now time.Time) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		if t.After(now) {
+			return time.Time{}, fmt.Errorf("%s is in the future", v)
+		}
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * 24 * time.Hour), nil
+		}
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("must be an RFC3339 time such as 2024-01-02T15:04:05Z or a duration such as 72h or 3d")
+}
+
+// envDuration reads a non-negative duration such as "500ms", "2s" or "7d", returning
+// def when unset. A whole number of days may be given with a "d" suffix.
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def, nil
+	}
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative duration like 500ms, 2h or 7d", key, v)
+	}
+	return d, nil
+}
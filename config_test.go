@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecipients(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "comma separated with spaces",
+			env:  map[string]string{"RECIPIENTS": " a@example.com ,b@example.com,, Carol <c@example.com>"},
+			want: "a@example.com,b@example.com,c@example.com",
+		},
+		{
+			name: "legacy TO_EMAILS",
+			env:  map[string]string{"TO_EMAILS": "a@example.com"},
+			want: "a@example.com",
+		},
+		{
+			name: "RECIPIENTS wins",
+			env:  map[string]string{"RECIPIENTS": "a@example.com", "TO_EMAILS": "old@example.com"},
+			want: "a@example.com",
+		},
+		{
+			name:    "malformed entries listed",
+			env:     map[string]string{"RECIPIENTS": "a@example.com,not-an-email,b@"},
+			wantErr: `malformed email addresses: "not-an-email", "b@"`,
+		},
+		{
+			name:    "unset with a sender",
+			env:     map[string]string{"FROM_EMAIL": "bot@example.com"},
+			wantErr: "RECIPIENTS is empty",
+		},
+		{
+			name: "unset without email",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg, err := loadConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(cfg.ToEmails, ","); got != tt.want {
+				t.Errorf("ToEmails = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// deliveryState records which recipients already received the current digest,
+// so a retried run only sends to the ones that were missed
+type deliveryState struct {
+	DigestID  string   `json:"digestId"`
+	Delivered []string `json:"delivered"`
+
+	path string
+}
+
+// deliveryStatePath returns the delivery tracking file kept next to the state file
+func deliveryStatePath(stateFile string) string {
+	return stateFile + ".delivery.json"
+}
+
+// digestID identifies a digest by the articles it contains
+func digestID(articles []Article) string {
+	h := sha256.New()
+	for _, article := range articles {
+		h.Write([]byte(article.UUID))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// loadDeliveryState reads delivery tracking for the given digest. Tracking left
+// over from a different digest is discarded, since a new digest has begun.
+func loadDeliveryState(path, id string) (*deliveryState, error) {
+	ds := &deliveryState{DigestID: id, path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ds, nil
+		}
+		return nil, fmt.Errorf("failed to read delivery state: %w", err)
+	}
+
+	var saved deliveryState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse delivery state: %w", err)
+	}
+
+	if saved.DigestID == id {
+		ds.Delivered = saved.Delivered
+	}
+	return ds, nil
+}
+
+// pending returns the recipients that have not received this digest yet
+func (ds *deliveryState) pending(recipients []string) []string {
+	delivered := make(map[string]bool, len(ds.Delivered))
+	for _, email := range ds.Delivered {
+		delivered[email] = true
+	}
+
+	var pending []string
+	for _, email := range recipients {
+		if !delivered[email] {
+			pending = append(pending, email)
+		}
+	}
+	return pending
+}
+
+// markDelivered records successful deliveries and persists them immediately. Like
+// the state file, the tracking file is replaced atomically, so a crash mid-write
+// cannot lose the recipients already recorded.
+func (ds *deliveryState) markDelivered(emails ...string) error {
+	ds.Delivered = append(ds.Delivered, emails...)
+
+	data, err := json.MarshalIndent(ds, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery state: %w", err)
+	}
+
+	if dir := filepath.Dir(ds.path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create state directory %s: %w", dir, err)
+		}
+	}
+	return writeOutput(ds.path, true, func(w io.Writer) {
+		w.Write(append(data, '\n'))
+	})
+}
+
+// clear removes the tracking file once every recipient has the digest
+func (ds *deliveryState) clear() error {
+	if err := os.Remove(ds.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear delivery state: %w", err)
+	}
+	return nil
+}
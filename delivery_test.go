@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// sendGridRecipients lists the recipients of a SendGrid request body
+func sendGridRecipients(t *testing.T, r *http.Request) []string {
+	t.Helper()
+	var payload SendGridEmail
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		t.Fatalf("SendGrid payload is not JSON: %v", err)
+	}
+	var emails []string
+	for _, p := range payload.Personalizations {
+		for _, to := range p.To {
+			emails = append(emails, to.Email)
+		}
+	}
+	return emails
+}
+
+func TestDeliveryResumesAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(t)
+	cfg.StateFile = filepath.Join(dir, "state.json")
+	cfg.FromEmail = "bot@example.com"
+	cfg.ToEmails = []string{"a@example.com", "b@example.com", "c@example.com"}
+	cfg.EmailProvider, cfg.SendGridAPIKey, cfg.SendGridBatchSize = providerSendGrid, "key", 1
+	articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z")}
+
+	// The first run reaches a and b, then the provider fails for c as if the
+	// process had died before getting to it
+	var sent []string
+	failFor := "c@example.com"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		to := sendGridRecipients(t, r)
+		if to[0] == failFor {
+			http.Error(w, `{"errors": [{"message": "rejected"}]}`, http.StatusBadRequest)
+			return
+		}
+		sent = append(sent, to...)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	cfg.HTTPClient = serverClient(srv)
+
+	if err := deliverEmail(context.Background(), cfg, articles, cfg.Location); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(sent, ","); got != "a@example.com,b@example.com" {
+		t.Fatalf("first run sent to %s", got)
+	}
+	delivery, err := loadDeliveryState(deliveryStatePath(cfg.StateFile), digestID(articles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(delivery.Delivered)
+	if got := strings.Join(delivery.Delivered, ","); got != "a@example.com,b@example.com" {
+		t.Fatalf("recorded deliveries %s", got)
+	}
+
+	// The retried run only sends to c, then clears the tracking
+	sent, failFor = nil, ""
+	if err := deliverEmail(context.Background(), cfg, articles, cfg.Location); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(sent, ","); got != "c@example.com" {
+		t.Errorf("retried run sent to %s, want c@example.com only", got)
+	}
+	if _, err := os.Stat(deliveryStatePath(cfg.StateFile)); !os.IsNotExist(err) {
+		t.Errorf("delivery tracking left behind after every recipient got the digest: %v", err)
+	}
+}
+
+func TestDeliveryStateNewDigest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json.delivery.json")
+	first, err := loadDeliveryState(path, "digest-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.markDelivered("a@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tracking of an earlier digest is discarded once a new one begins
+	next, err := loadDeliveryState(path, "digest-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := next.pending([]string{"a@example.com", "b@example.com"}); len(pending) != 2 {
+		t.Errorf("pending for a new digest = %v, want every recipient", pending)
+	}
+}
+
+func TestMarkDeliveredReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json.delivery.json")
+	ds, err := loadDeliveryState(path, "digest-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.markDelivered("a@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	before := readFile(t, path)
+
+	// A reader of the old file keeps seeing it whole, so it was not rewritten in place
+	old, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	if err := ds.markDelivered("b@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(old); string(data) != before {
+		t.Errorf("old file now reads %q, want %q", data, before)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d files, want no temp file left", len(entries))
+	}
+
+	reloaded, err := loadDeliveryState(path, "digest-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := reloaded.pending([]string{"a@example.com", "b@example.com", "c@example.com"}); strings.Join(pending, ",") != "c@example.com" {
+		t.Errorf("pending = %v, want only c@example.com", pending)
+	}
+}
+
+func TestAutoCompactOnOversize(t *testing.T) {
+	tests := []struct {
+		name          string
+		autoCompact   bool
+		compactStatus int
+		wantLayouts   string
+		wantAccepted  bool
+	}{
+		{"compact retry succeeds", true, http.StatusAccepted, "full,compact", true},
+		{"compact retry fails too", true, http.StatusRequestEntityTooLarge, "full,compact", false},
+		{"disabled", false, http.StatusAccepted, "full", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.StateFile = filepath.Join(t.TempDir(), "state.json")
+			cfg.FromEmail, cfg.ToEmails = "bot@example.com", []string{"a@example.com"}
+			cfg.EmailProvider, cfg.SendGridAPIKey = providerSendGrid, "key"
+			cfg.AutoCompact = tt.autoCompact
+
+			var layouts []string
+			accepted := false
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload SendGridEmail
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("SendGrid payload is not JSON: %v", err)
+				}
+				html := payload.Content[len(payload.Content)-1].Value
+				if !strings.Contains(html, `class="article compact"`) {
+					layouts = append(layouts, "full")
+					http.Error(w, `{"errors": [{"message": "payload too large"}]}`, http.StatusRequestEntityTooLarge)
+					return
+				}
+				layouts = append(layouts, "compact")
+				accepted = tt.compactStatus == http.StatusAccepted
+				w.WriteHeader(tt.compactStatus)
+			}))
+			defer srv.Close()
+			cfg.HTTPClient = serverClient(srv)
+
+			articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z")}
+			if err := deliverEmail(context.Background(), cfg, articles, cfg.Location); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(layouts, ","); got != tt.wantLayouts {
+				t.Errorf("sent layouts %s, want %s", got, tt.wantLayouts)
+			}
+			if accepted != tt.wantAccepted {
+				t.Errorf("accepted = %v, want %v", accepted, tt.wantAccepted)
+			}
+		})
+	}
+}
@@ -0,0 +1,728 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// exitTimeLimited is the exit code of a run that MAX_RUNTIME cut short after
+// delivering what it had fetched
+const exitTimeLimited = 3
+
+// runDigest fetches new articles, delivers them and advances the saved cutoff.
+// It returns the process exit code.
+func runDigest(ctx context.Context, cfg Config, enableEmail bool) int {
+	loc := cfg.Location
+
+	// MAX_RUNTIME bounds the whole run; the fetch stops early enough to leave
+	// time for delivering what it collected
+	fetchCtx := ctx
+	if cfg.MaxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.MaxRuntime)
+		defer cancel()
+		fetchCtx, cancel = context.WithTimeout(ctx, cfg.MaxRuntime-min(cfg.MaxRuntime/5, maxDeliveryReserve))
+		defer cancel()
+	}
+
+	// Validate configuration
+	if cfg.DryRun {
+		fmt.Println("Dry run: no email is sent and no files or state are written.")
+	} else if !enableEmail && !cfg.EnableFileOutput && len(cfg.Notifiers(nil)) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Either email, Slack, Discord, a read-later service or file output must be enabled\n")
+		return 1
+	}
+
+	source, err := newArticleSource(cfg.Source, cfg.ClientOptions())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Invalid SOURCE: %v\n", err)
+		return 1
+	}
+
+	// Read the state left by the previous run
+	state, err := readState(cfg.StateFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading state file: %v\n", err)
+		return 1
+	}
+
+	if cfg.MinRunInterval > 0 && !cfg.DryRun {
+		if since := time.Since(state.LastRun); !cfg.Force && since < cfg.MinRunInterval {
+			fmt.Printf("The last run started %s ago, within MIN_RUN_INTERVAL (%s); skipping. Use --force to run anyway.\n",
+				since.Round(time.Second), cfg.MinRunInterval)
+			return 0
+		}
+		state.LastRun = time.Now()
+		if err := writeState(cfg.StateFile, state); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to record the run time: %v\n", err)
+		}
+	}
+	if cfg.CompressOlderThan > 0 && !cfg.DryRun {
+		defer compressArchives(cfg)
+	}
+	if cfg.WeeklyReport && enableEmail && !cfg.DryRun {
+		defer sendWeeklyReport(ctx, cfg, time.Now())
+	}
+	lastProcessed := state.LastProcessed
+	maxAge := cfg.MaxAge
+
+	now := time.Now()
+	switch {
+	case !cfg.Since.IsZero():
+		// -since replaces the saved cutoff and seen articles for this run; the
+		// state itself is kept and only ever advanced
+		fmt.Printf("Using -since instead of the state file: %s\n", cfg.Since.In(loc).Format("2006-01-02 03:04 PM MST"))
+		lastProcessed, maxAge = cfg.Since, 0
+	case lastProcessed.IsZero():
+		fmt.Println("First run - fetching articles from last 24 hours...")
+	default:
+		fmt.Printf("Last processed: %s\n", lastProcessed.In(loc).Format("2006-01-02 03:04 PM MST"))
+	}
+
+	cutoffTime := resolveCutoff(lastProcessed, maxAge, now)
+	if maxAge > 0 {
+		if cutoffTime.After(resolveCutoff(lastProcessed, 0, now)) {
+			fmt.Printf("MAX_AGE (%s) is more recent than the cutoff; using it instead.\n", cfg.MaxAge)
+		} else {
+			fmt.Printf("Cutoff is within MAX_AGE (%s); keeping it.\n", cfg.MaxAge)
+		}
+	}
+
+	fmt.Printf("Fetching articles published after %s...\n", cutoffTime.In(loc).Format("2006-01-02 03:04 PM MST"))
+
+	// When the previous run recorded what it reported, fetch a little before the
+	// cutoff so articles sharing its second aren't lost; the repeats are dropped below
+	fetchAfter := cutoffTime
+	if len(state.SeenUUIDs) > 0 && cutoffTime.Equal(lastProcessed) && cfg.Since.IsZero() {
+		fetchAfter = cutoffTime.Add(-stateOverlap)
+	}
+
+	// Fetch all articles after cutoff time from the configured source
+	fetchStart := time.Now()
+	fetched, err := fetchWithRetries(fetchCtx, source, fetchAfter, cfg.RunRetries)
+	fetchDuration := time.Since(fetchStart)
+
+	timeLimited := false
+	if err != nil && fetchCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Warning: MAX_RUNTIME (%s) is nearly up; stopped fetching with %d articles collected\n", cfg.MaxRuntime, len(fetched))
+		// Articles between the cutoff and the oldest one fetched are still
+		// missing, so the cutoff stays put and the delivered ones are recorded as seen
+		timeLimited, err = true, nil
+		if cfg.Since.IsZero() {
+			state.LastProcessed = cutoffTime
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching discuss articles: %v\n", err)
+		if hint := fetchErrorHint(err); hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+		}
+		return 1
+	}
+
+	warnMissingKeys(fetched, cfg.DedupKey)
+	if len(state.SeenUUIDs) > 0 && cfg.Since.IsZero() {
+		if fresh := excludeSeenKeys(fetched, state.seen(), cfg.DedupKey); len(fresh) < len(fetched) {
+			fmt.Printf("Skipped %d articles already reported by a previous run.\n", len(fetched)-len(fresh))
+			fetched = fresh
+		}
+	}
+
+	// ALWAYS_SHOW_LATEST shows the latest articles even when none of them are new
+	var latest []Article
+	if cfg.AlwaysShowLatest > 0 && cfg.ChunkWindow == 0 {
+		if latest, err = source.FetchLatest(fetchCtx, cfg.AlwaysShowLatest); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to fetch the latest articles: %v\n", err)
+		}
+	}
+
+	if len(fetched) == 0 && len(latest) == 0 {
+		fmt.Println("No new articles found.")
+		if cfg.DryRun {
+			return 0
+		}
+		recordRunStats(cfg, nil, fetchDuration)
+		if exitCode := finishEmptyRun(cfg, loc); exitCode != 0 || !timeLimited {
+			return exitCode
+		}
+		return exitTimeLimited
+	}
+
+	if len(fetched) == 0 {
+		fmt.Println("No new articles found; showing the latest ones.")
+	} else {
+		fmt.Printf("Found %d articles published after cutoff time.\n", len(fetched))
+	}
+
+	var exitCode int
+	if cfg.ChunkWindow > 0 {
+		exitCode = deliverChunks(ctx, cfg, enableEmail, state, fetched, cutoffTime, !timeLimited, fetchDuration, loc)
+	} else {
+		exitCode, _ = deliverArticles(ctx, cfg, enableEmail, state, fetched, latest, !timeLimited, fetchDuration, loc)
+	}
+	if exitCode == 0 && timeLimited {
+		return exitTimeLimited
+	}
+	return exitCode
+}
+
+// compressArchives applies COMPRESS_OLDER_THAN to fetched_articles, logging the
+// outcome; a failure does not fail the run
+func compressArchives(cfg Config) {
+	n, err := compressOldArchives("fetched_articles", cfg.FilenameTemplate, cfg.RunLabel, cfg.CompressOlderThan, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if n > 0 {
+		fmt.Printf("✓ Compressed %d archive files older than %s\n", n, cfg.CompressOlderThan)
+	}
+}
+
+// firstRunWindow is how far back the first run fetches
+const firstRunWindow = 24 * time.Hour
+
+// resolveCutoff returns the time after which articles are fetched: the last
+// processed time, or firstRunWindow ago on the first run, moved forward to
+// maxAge ago when that is more recent
+func resolveCutoff(lastProcessed time.Time, maxAge time.Duration, now time.Time) time.Time {
+	cutoff := lastProcessed
+	if cutoff.IsZero() {
+		cutoff = now.Add(-firstRunWindow)
+	}
+	if maxAge > 0 {
+		if oldest := now.Add(-maxAge); oldest.After(cutoff) {
+			cutoff = oldest
+		}
+	}
+	return cutoff
+}
+
+// deliverChunks splits the fetched articles into CHUNK_WINDOW windows starting at
+// the cutoff and delivers them oldest first, advancing the state after each one.
+// It stops at the first window that fails.
+func deliverChunks(ctx context.Context, cfg Config, enableEmail bool, state State, fetched []Article, cutoffTime time.Time, advance bool, fetchDuration time.Duration, loc *time.Location) int {
+	chunks := chunkByWindow(fetched, cutoffTime, cfg.ChunkWindow)
+	template := cfg.FilenameTemplate
+	for i, chunk := range chunks {
+		fmt.Printf("\n=== Window %d of %d: %d articles ===\n", i+1, len(chunks), len(chunk))
+		if !cfg.DailyFile {
+			// The windows are written within the same second, so number their files
+			cfg.FilenameTemplate = suffixedTemplate(template, fmt.Sprintf("_part%d", i+1))
+		}
+		exitCode, next := deliverArticles(ctx, cfg, enableEmail, state, chunk, nil, advance, fetchDuration, loc)
+		if exitCode != 0 {
+			return exitCode
+		}
+		// The fetch is only timed once, for the first window's stats
+		state, fetchDuration = next, 0
+	}
+	return 0
+}
+
+// chunkByWindow groups articles into consecutive windows of the given length
+// starting at start, oldest window first. Empty windows are left out and articles
+// keep their order within a window.
+func chunkByWindow(articles []Article, start time.Time, window time.Duration) [][]Article {
+	byIndex := make(map[int64][]Article)
+	var indices []int64
+	for _, article := range articles {
+		var index int64
+		if t, err := parseArticleTime(article.CreatedAt); err == nil && t.After(start) {
+			index = int64(t.Sub(start) / window)
+		}
+		if _, ok := byIndex[index]; !ok {
+			indices = append(indices, index)
+		}
+		byIndex[index] = append(byIndex[index], article)
+	}
+	sort.Slice(indices, func(a, b int) bool { return indices[a] < indices[b] })
+
+	chunks := make([][]Article, 0, len(indices))
+	for _, index := range indices {
+		chunks = append(chunks, byIndex[index])
+	}
+	return chunks
+}
+
+// deliverArticles filters the fetched articles, emails and saves them and updates
+// the state, advancing the cutoff if advance is set. Articles from latest that are
+// not new fill the digest up to ALWAYS_SHOW_LATEST. It returns the exit code and
+// the state the next delivery starts from.
+func deliverArticles(ctx context.Context, cfg Config, enableEmail bool, state State, fetched, latest []Article, advance bool, fetchDuration time.Duration, loc *time.Location) (int, State) {
+	articles := filterArticles(fetched, cfg)
+	if len(articles) < len(fetched) {
+		fmt.Printf("%d articles remain after filtering.\n", len(articles))
+	}
+
+	if cfg.ArchiveIndex != "" {
+		archived, err := loadArchiveIndex(cfg.ArchiveIndex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1, state
+		}
+		if fresh := excludeSeenArticles(articles, archived); len(fresh) < len(articles) {
+			fmt.Printf("Skipped %d already archived articles.\n", len(articles)-len(fresh))
+			articles = fresh
+		}
+	}
+	if len(latest) > 0 {
+		articles = withLatest(articles, filterArticles(latest, cfg), cfg.AlwaysShowLatest, cfg.DedupKey)
+	}
+	if cfg.MaxArticles > 0 && len(articles) > cfg.MaxArticles {
+		// The state still advances past the dropped articles, so they are not
+		// reported on the next run either
+		fmt.Printf("Reporting the %d most recent of %d articles (MAX_ARTICLES).\n", cfg.MaxArticles, len(articles))
+		cfg.MatchedArticles = len(articles)
+		articles = capMostRecent(articles, cfg.MaxArticles)
+	}
+	sortArticles(articles, cfg)
+
+	if len(articles) == 0 {
+		fmt.Println("No articles matched the configured filters.")
+		if cfg.DryRun {
+			return 0, state
+		}
+		state = updateLastProcessed(cfg.StateFile, cfg.DedupKey, state, fetched, advance, loc)
+		recordRunStats(cfg, nil, fetchDuration)
+		return finishEmptyRun(cfg, loc), state
+	}
+
+	// Print article summary
+	for i, article := range articles {
+		creationTime := formatStringTimestamp(article.CreatedAt, cfg.DateFormat.DateTime, cfg.Location)
+		fmt.Printf("\n%d. %s", i+1, displayTitle(article.Title, cfg.TitleMaxLen))
+		if cfg.Verbose {
+			fmt.Printf(" [%d tags, %d reactions]", len(article.Tags), totalReactions(article))
+		}
+		if status := articleStatus(article, cfg); status != "" {
+			fmt.Printf(" (%s)", status)
+		}
+		fmt.Println()
+		fmt.Printf("   Created: %s\n", creationTime)
+		fmt.Printf("   URL: %s\n", articleURL(article))
+	}
+
+	if cfg.DryRun {
+		fmt.Printf("\nDry run: %d articles would be delivered.\n", len(articles))
+		return 0, state
+	}
+
+	if cfg.FullContent {
+		fmt.Printf("\nFetching the full content of %d articles...\n", len(articles))
+		fetchFullContent(ctx, articles, cfg.ClientOptions())
+	}
+
+	// Send email if configured
+	exitCode := 0
+	if enableEmail {
+		if err := deliverEmail(ctx, cfg, articles, loc); err != nil {
+			// Every recipient was rejected; still write files, but fail the run
+			exitCode = 1
+		}
+	}
+	saved := newSavedURLs(state.SavedURLs)
+	for _, notifier := range cfg.Notifiers(saved) {
+		deliverNotification(ctx, notifier, cfg, articles)
+	}
+	state.SavedURLs = saved.recent(maxSavedURLs)
+
+	// Write to file if enabled
+	var outputFile string
+	if cfg.EnableFileOutput {
+		var err error
+		if outputFile, err = writeDigestFile(cfg, articles, loc); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing articles to file: %v\n", err)
+			return 1, state
+		}
+		if cfg.ArchiveIndex != "" {
+			if err := appendArchiveIndex(cfg.ArchiveIndex, newArticles(articles)); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+
+	// Update last processed timestamp with the most recent fetched article
+	state = updateLastProcessed(cfg.StateFile, cfg.DedupKey, state, fetched, advance, loc)
+	recordRunStats(cfg, articles, fetchDuration)
+	if cfg.WeeklyReport {
+		if err := recordWeeklyArticles(cfg, articles, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if exitCode != 0 {
+		return exitCode, state
+	}
+	return runPostRunHook(cfg, outputFile, len(articles)), state
+}
+
+// recordRunStats appends the run's stats line when STATS_JSONL is set
+func recordRunStats(cfg Config, articles []Article, fetchDuration time.Duration) {
+	if cfg.StatsJSONL == "" {
+		return
+	}
+	if err := appendStats(cfg.StatsJSONL, articles, fetchDuration, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// maxDeliveryReserve caps the part of MAX_RUNTIME kept for delivery after the
+// fetch; shorter runtimes reserve a fifth
+const maxDeliveryReserve = 30 * time.Second
+
+// fetchRetryDelay is the wait before the first repeat of a failed fetch; it doubles
+// with each further attempt. It is a variable so tests can shorten it.
+var fetchRetryDelay = 5 * time.Second
+
+// fetchWithRetries runs the whole fetch again from scratch up to retries more times
+// when it fails. Authentication failures are returned at once since repeating the
+// same request cannot fix them. When ctx ends, the articles the last attempt
+// collected are returned with the error.
+func fetchWithRetries(ctx context.Context, source ArticleSource, cutoffTime time.Time, retries int) ([]Article, error) {
+	delay := fetchRetryDelay
+	for attempt := 0; ; attempt++ {
+		articles, err := source.FetchArticlesAfter(ctx, cutoffTime)
+		if err == nil {
+			return articles, nil
+		}
+		if ctx.Err() != nil {
+			return articles, err
+		}
+
+		var fetchErr *FetchError
+		if attempt >= retries || (errors.As(err, &fetchErr) && fetchErr.AuthFailed()) {
+			return nil, err
+		}
+
+		fmt.Fprintf(os.Stderr, "Warning: fetch failed (%v), retrying in %s (%d of %d)...\n", err, delay, attempt+1, retries)
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return articles, sleepErr
+		}
+		delay *= 2
+	}
+}
+
+// fetchErrorHint suggests what to do about a failed fetch, based on its kind
+func fetchErrorHint(err error) string {
+	var fetchErr *FetchError
+	if !errors.As(err, &fetchErr) {
+		return ""
+	}
+	switch {
+	case fetchErr.RateLimited():
+		return "LeetCode is rate limiting requests; wait before the next run or run less often."
+	case fetchErr.AuthFailed():
+		return "LeetCode rejected the request; check LEETCODE_SESSION and USER_AGENT."
+	case fetchErr.ServerError():
+		return "LeetCode had a server error; the next run should pick up where this one left off."
+	default:
+		return ""
+	}
+}
+
+// finishEmptyRun handles a run with nothing to report. A header-only file is
+// written only when WRITE_EMPTY_FILE is enabled.
+func finishEmptyRun(cfg Config, loc *time.Location) int {
+	if cfg.TouchOnEmpty {
+		if err := touchEmptyRunMarker(loc); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	var outputFile string
+	if cfg.EnableFileOutput && cfg.WriteEmptyFile {
+		var err error
+		if outputFile, err = writeDigestFile(cfg, nil, loc); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing articles to file: %v\n", err)
+			return 1
+		}
+	}
+	return runPostRunHook(cfg, outputFile, 0)
+}
+
+// touchEmptyRunMarker writes last_run_empty_<date>.txt holding the time of the
+// check, as evidence that a run happened even though it found nothing
+func touchEmptyRunMarker(loc *time.Location) error {
+	if err := os.MkdirAll("fetched_articles", 0755); err != nil {
+		return fmt.Errorf("failed to create fetched_articles directory: %w", err)
+	}
+
+	now := time.Now().In(loc)
+	filename := filepath.Join("fetched_articles", "last_run_empty_"+now.Format("2006-01-02")+".txt")
+	if err := os.WriteFile(filename, []byte(now.Format(time.RFC3339)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write empty run marker: %w", err)
+	}
+	fmt.Printf("✓ Recorded empty run in %s\n", filename)
+	return nil
+}
+
+// writeDigestFile writes the articles to the output directory using the configured
+// naming and returns the path written
+func writeDigestFile(cfg Config, articles []Article, loc *time.Location) (string, error) {
+	// Ensure fetched_articles directory exists
+	if err := os.MkdirAll("fetched_articles", 0755); err != nil {
+		return "", fmt.Errorf("failed to create fetched_articles directory: %w", err)
+	}
+
+	name, err := renderFilename(cfg.FilenameTemplate, time.Now().In(loc), len(articles), formatExtension(cfg.OutputFormat), cfg.RunLabel)
+	if err != nil {
+		return "", err
+	}
+
+	filename := filepath.Join("fetched_articles", name)
+	written := len(articles)
+
+	// A named pipe can be neither read back to append to nor verified
+	pipe := isNamedPipe(filename)
+	daily := cfg.DailyFile && !pipe
+	switch {
+	case cfg.OutputFormat == formatJSON && daily:
+		written, err = appendArticlesToJSON(articles, filename, cfg)
+	case cfg.OutputFormat == formatJSON:
+		err = writeArticlesToJSON(articles, filename, cfg)
+	case cfg.OutputFormat == formatMarkdown && daily:
+		written, err = appendArticlesToMarkdown(articles, filename, cfg)
+	case cfg.OutputFormat == formatMarkdown:
+		err = writeArticlesToMarkdown(articles, filename, cfg)
+	case cfg.OutputFormat == formatRSS && daily:
+		written, err = appendArticlesToRSS(articles, filename, cfg)
+	case cfg.OutputFormat == formatRSS:
+		err = writeArticlesToRSS(articles, filename, cfg)
+	case daily:
+		written, err = appendArticlesToFile(articles, filename, cfg)
+	default:
+		err = writeArticlesToFile(articles, filename, cfg)
+	}
+	if err != nil {
+		return "", err
+	}
+	if cfg.VerifyWrite && !pipe {
+		if err := verifyOutputFile(filename, cfg.OutputFormat); err != nil {
+			return "", err
+		}
+	}
+
+	fmt.Printf("✓ Successfully saved %d articles to %s\n", written, filename)
+	return filename, nil
+}
+
+// updateLastProcessed saves the creation time of the newest article as the next
+// cutoff, and adds the articles' DEDUP_KEY values to the recently seen ones.
+// Without advance the cutoff is kept and only the seen articles are added. It
+// returns the saved state, or prev if nothing was saved.
+func updateLastProcessed(stateFile, dedupKey string, prev State, articles []Article, advance bool, loc *time.Location) State {
+	if len(articles) == 0 {
+		return prev
+	}
+
+	// Articles are not always newest first (see ORDER_BY), so look at all of them
+	var newestTime time.Time
+	seen := prev.SeenUUIDs
+	for _, article := range articles {
+		if t, err := parseArticleTime(article.CreatedAt); err == nil && t.After(newestTime) {
+			newestTime = t
+		}
+		if key := articleKey(article, dedupKey); key != "" {
+			seen = append(seen, key)
+		}
+	}
+	if !advance || newestTime.Before(prev.LastProcessed) {
+		// A -since run can fetch only articles older than the saved cutoff
+		newestTime = prev.LastProcessed
+	}
+	if newestTime.IsZero() {
+		return prev
+	}
+	if len(seen) > maxSeenUUIDs {
+		seen = seen[len(seen)-maxSeenUUIDs:]
+	}
+
+	state := prev
+	state.LastProcessed, state.SeenUUIDs = newestTime, seen
+	if err := writeState(stateFile, state); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to update last processed timestamp: %v\n", err)
+		return prev
+	}
+	if advance {
+		fmt.Printf("Updated last processed timestamp to: %s\n", newestTime.In(loc).Format("2006-01-02 03:04 PM MST"))
+	} else {
+		fmt.Printf("Kept last processed timestamp at %s and recorded %d delivered articles\n",
+			newestTime.In(loc).Format("2006-01-02 03:04 PM MST"), len(articles))
+	}
+	return state
+}
+
+// emailRetryDelay is how long to wait before retrying a transient email failure
+var emailRetryDelay = 5 * time.Second
+
+// deliverEmail sends the digest to every recipient that has not received it yet.
+// Failures are logged so the run can continue with file output; an error is
+// returned only when the server synchronously rejected every recipient.
+func deliverEmail(ctx context.Context, cfg Config, articles []Article, loc *time.Location) error {
+	fmt.Println("\nSending email...")
+
+	delivery, err := loadDeliveryState(deliveryStatePath(cfg.StateFile), digestID(articles))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error sending email: %v\n", err)
+		return nil
+	}
+
+	recipients := delivery.pending(cfg.ToEmails)
+	if len(recipients) == 0 {
+		fmt.Println("All recipients already received this digest.")
+		return nil
+	}
+	if len(recipients) < len(cfg.ToEmails) {
+		fmt.Printf("Resuming delivery: %d of %d recipients still pending.\n", len(recipients), len(cfg.ToEmails))
+	}
+
+	err = sendDigestEmail(ctx, cfg, articles, recipients, loc)
+
+	var emailErr *EmailError
+	if errors.As(err, &emailErr) && emailErr.Transient() {
+		fmt.Fprintf(os.Stderr, "Warning: Email send failed (%v), retrying once...\n", err)
+		prev := err
+		if err = sleepContext(ctx, emailRetryDelay); err == nil {
+			err = retryRejected(prev, recipients, func(to []string) error {
+				return sendDigestEmail(ctx, cfg, articles, to, loc)
+			})
+		}
+	}
+	// A partly delivered message was not too large for the recipients that got it
+	if messageTooLarge(err) && !errors.As(err, new(*RecipientError)) && cfg.AutoCompact && cfg.EmailFormat.Layout != layoutCompact {
+		err = sendCompactFallback(ctx, cfg, articles, recipients, loc, err)
+	}
+	if errors.As(err, &emailErr) && emailErr.AuthFailed() {
+		fmt.Fprintf(os.Stderr, "Hint: The email provider rejected the credentials; check %s.\n", providerCredentials[cfg.EmailProvider])
+	}
+
+	var recipientErr *RecipientError
+	switch {
+	case errors.As(err, &recipientErr) && recipientErr.AllRejected():
+		fmt.Fprintf(os.Stderr, "Error sending email: %v\n", err)
+		return err
+	case errors.As(err, &recipientErr):
+		for email, rejection := range recipientErr.Rejected {
+			fmt.Fprintf(os.Stderr, "Warning: Recipient %s was rejected: %v\n", email, rejection)
+		}
+		recipients = recipientErr.Accepted
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error sending email: %v\n", err)
+		return nil
+	}
+
+	if err := delivery.markDelivered(recipients...); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to record email delivery: %v\n", err)
+	}
+	fmt.Printf("✓ Successfully sent email to: %s\n", strings.Join(recipients, ", "))
+
+	if len(delivery.pending(cfg.ToEmails)) == 0 {
+		if err := delivery.clear(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// deliverNotification posts the digest to a chat channel. Failures are logged and
+// returned, so a digest run can carry on with the other outputs.
+func deliverNotification(ctx context.Context, notifier Notifier, cfg Config, articles []Article) error {
+	fmt.Printf("\nPosting to %s...\n", notifier.Name())
+
+	err := notifier.Notify(ctx, articles, cfg)
+	var slackErr *SlackError
+	switch {
+	case errors.As(err, &slackErr) && slackErr.InvalidPayload():
+		fmt.Fprintf(os.Stderr, "Error posting to Slack: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Hint: Slack rejected the message blocks as malformed; an article title or summary may contain text Slack cannot render.\n")
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error posting to %s: %v\n", notifier.Name(), err)
+	default:
+		fmt.Printf("✓ Posted %d articles to %s\n", len(articles), notifier.Name())
+	}
+	return err
+}
+
+// retryRejected resends after a failed send. When only some recipients were
+// rejected, just those are retried and the outcome is merged with the first
+// attempt, so accepted recipients don't get a second copy.
+func retryRejected(prev error, recipients []string, send func([]string) error) error {
+	var partial *RecipientError
+	if !errors.As(prev, &partial) || partial.AllRejected() {
+		return send(recipients)
+	}
+
+	var retry []string
+	for _, email := range recipients {
+		if _, ok := partial.Rejected[email]; ok {
+			retry = append(retry, email)
+		}
+	}
+	err := send(retry)
+	if err == nil {
+		return nil
+	}
+
+	merged := &RecipientError{Accepted: partial.Accepted, Rejected: make(map[string]error)}
+	var again *RecipientError
+	if errors.As(err, &again) {
+		merged.Accepted = append(merged.Accepted, again.Accepted...)
+		maps.Copy(merged.Rejected, again.Rejected)
+	} else {
+		for _, email := range retry {
+			merged.Rejected[email] = err
+		}
+	}
+	return merged
+}
+
+// sendCompactFallback resends a digest that was rejected as too large using the
+// compact layout. If that fails too, the original error is returned.
+func sendCompactFallback(ctx context.Context, cfg Config, articles []Article, recipients []string, loc *time.Location, sizeErr error) error {
+	fmt.Fprintf(os.Stderr, "Warning: Email was rejected as too large (%v), retrying with the compact layout...\n", sizeErr)
+
+	cfg.EmailFormat.Layout = layoutCompact
+	cfg.FeatureTopN = 0
+	err := sendDigestEmail(ctx, cfg, articles, recipients, loc)
+
+	// A recipient error means the compact message itself was accepted
+	if err != nil && !errors.As(err, new(*RecipientError)) {
+		fmt.Fprintf(os.Stderr, "Warning: Compact email failed as well: %v\n", err)
+		return sizeErr
+	}
+	fmt.Println("Sent the compact layout instead of the full one.")
+	return err
+}
+
+// sendDigestEmail renders the digest and sends it to the given recipients
+func sendDigestEmail(ctx context.Context, cfg Config, articles []Article, recipients []string, loc *time.Location) error {
+	attachments, err := overflowAttachments(articles, cfg)
+	if err != nil {
+		return err
+	}
+
+	msg := emailMessage{
+		FromEmail:   cfg.FromEmail,
+		FromName:    cfg.FromName,
+		Subject:     fmt.Sprintf("📚 %s - %d New Articles", emailTitle(cfg.RunLabel), len(newArticles(articles))),
+		Text:        generatePlainTextEmail(articles, loc, cfg),
+		HTML:        generateHTMLEmail(articles, loc, cfg),
+		Attachments: attachments,
+	}
+	if msg.FromName == "" {
+		msg.FromName = "LeetCode Articles Bot"
+	}
+	if cfg.EmailProvider == providerSendGrid && cfg.SendGridTemplateID != "" {
+		msg.TemplateID = cfg.SendGridTemplateID
+		msg.TemplateData = buildTemplateData(msg.Subject, articles, cfg)
+	}
+
+	return cfg.Sender().Send(ctx, msg, recipients)
+}
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFinishEmptyRun(t *testing.T) {
+	tests := []struct {
+		writeEmpty string
+		wantFiles  int
+	}{
+		{"", 0},
+		{"false", 0},
+		{"true", 1},
+	}
+	for _, tt := range tests {
+		t.Run("WRITE_EMPTY_FILE="+tt.writeEmpty, func(t *testing.T) {
+			inTempDir(t)
+			t.Setenv("WRITE_EMPTY_FILE", tt.writeEmpty)
+			cfg := testConfig(t)
+
+			if code := finishEmptyRun(cfg, cfg.Location); code != 0 {
+				t.Fatalf("finishEmptyRun() = %d, want 0", code)
+			}
+
+			files, _ := filepath.Glob(filepath.Join("fetched_articles", "*"))
+			if len(files) != tt.wantFiles {
+				t.Fatalf("fetched_articles holds %v, want %d files", files, tt.wantFiles)
+			}
+			if tt.wantFiles == 0 {
+				if _, err := os.Stat("fetched_articles"); err == nil {
+					t.Error("output directory created for an empty run")
+				}
+				return
+			}
+
+			content := readFile(t, files[0])
+			if !strings.Contains(content, "Latest 0 Articles") {
+				t.Errorf("empty file has no header:\n%s", content)
+			}
+			if strings.Contains(content, "Article #") {
+				t.Errorf("empty file lists articles:\n%s", content)
+			}
+		})
+	}
+}
+
+// flakySource fails its first failures fetches, then returns articles
+type flakySource struct {
+	failures int
+	err      error
+	articles []Article
+	calls    int
+}
+
+func (s *flakySource) FetchArticlesAfter(ctx context.Context, cutoffTime time.Time) ([]Article, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return nil, s.err
+	}
+	return s.articles, nil
+}
+
+func (s *flakySource) FetchLatest(ctx context.Context, n int) ([]Article, error) {
+	return s.FetchArticlesAfter(ctx, time.Time{})
+}
+
+func TestFetchWithRetries(t *testing.T) {
+	defer func(d time.Duration) { fetchRetryDelay = d }(fetchRetryDelay)
+	fetchRetryDelay = time.Millisecond
+
+	network := &FetchError{Err: errors.New("connection reset")}
+	auth := &FetchError{StatusCode: http.StatusForbidden}
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		retries   int
+		wantErr   bool
+		wantCalls int
+	}{
+		{"fails twice then succeeds", 2, network, 2, false, 3},
+		{"retries exhausted", 3, network, 2, true, 3},
+		{"no retries", 1, network, 0, true, 1},
+		{"auth failure is not retried", 2, auth, 5, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &flakySource{failures: tt.failures, err: tt.err, articles: []Article{testArticle("u1", "2024-05-01T10:00:00Z")}}
+			articles, err := fetchWithRetries(context.Background(), source, time.Time{}, tt.retries)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetchWithRetries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(articles) != 1 {
+				t.Errorf("got %d articles, want 1", len(articles))
+			}
+			if source.calls != tt.wantCalls {
+				t.Errorf("source called %d times, want %d", source.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestResolveCutoff(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	lastRun := now.Add(-6 * time.Hour)
+	tests := []struct {
+		name          string
+		lastProcessed time.Time
+		maxAge        time.Duration
+		want          time.Time
+	}{
+		{"first run", time.Time{}, 0, now.Add(-firstRunWindow)},
+		{"state only", lastRun, 0, lastRun},
+		{"state wins over an older max age", lastRun, 48 * time.Hour, lastRun},
+		{"max age wins over an older state", now.Add(-30 * 24 * time.Hour), 72 * time.Hour, now.Add(-72 * time.Hour)},
+		{"max age shortens the first run", time.Time{}, time.Hour, now.Add(-time.Hour)},
+	}
+	for _, tt := range tests {
+		if got := resolveCutoff(tt.lastProcessed, tt.maxAge, now); !got.Equal(tt.want) {
+			t.Errorf("%s: resolveCutoff() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTouchOnEmpty(t *testing.T) {
+	tests := []struct {
+		name       string
+		touch      string
+		articles   []Article
+		wantMarker bool
+	}{
+		{"disabled", "", nil, false},
+		{"empty run", "true", nil, true},
+		{"run with articles", "true", []Article{testArticle("u1", "2024-05-01T10:00:00Z")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			t.Setenv("TOUCH_ON_EMPTY", tt.touch)
+			cfg := testConfig(t)
+			cfg.StateFile = "state.json"
+
+			if code, _ := deliverArticles(context.Background(), cfg, false, State{}, tt.articles, nil, true, 0, cfg.Location); code != 0 {
+				t.Fatalf("deliverArticles() = %d, want 0", code)
+			}
+
+			markers, _ := filepath.Glob(filepath.Join("fetched_articles", "last_run_empty_*.txt"))
+			if got := len(markers) == 1; got != tt.wantMarker {
+				t.Fatalf("markers = %v, want marker %v", markers, tt.wantMarker)
+			}
+			if tt.wantMarker {
+				checked, err := time.Parse(time.RFC3339, strings.TrimSpace(readFile(t, markers[0])))
+				if err != nil || time.Since(checked) > time.Minute {
+					t.Errorf("marker holds %q, want the time of the check", readFile(t, markers[0]))
+				}
+			}
+		})
+	}
+}
+
+func TestRunLabel(t *testing.T) {
+	tests := []struct {
+		label       string
+		wantFile    string
+		wantHeader  string
+		wantEmailH1 string
+	}{
+		{"", "leetcode_articles_*.txt", "LeetCode Discuss - Latest 1 Articles", "LeetCode Daily Digest"},
+		{"morning", "leetcode_articles_*_morning.txt", "LeetCode Discuss - morning digest - Latest 1 Articles", "LeetCode Discuss — morning digest"},
+	}
+	for _, tt := range tests {
+		t.Run("label="+tt.label, func(t *testing.T) {
+			inTempDir(t)
+			t.Setenv("RUN_LABEL", tt.label)
+			cfg := testConfig(t)
+			articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z")}
+
+			path, err := writeDigestFile(cfg, articles, cfg.Location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok, _ := filepath.Match(tt.wantFile, filepath.Base(path)); !ok {
+				t.Errorf("file = %s, want %s", filepath.Base(path), tt.wantFile)
+			}
+			if header, _, _ := strings.Cut(readFile(t, path), "\n"); header != tt.wantHeader {
+				t.Errorf("file header = %q, want %q", header, tt.wantHeader)
+			}
+			if html := generateHTMLEmail(articles, cfg.Location, cfg); !strings.Contains(html, "<h1>"+tt.wantEmailH1+"</h1>") {
+				t.Errorf("HTML email lacks heading %q", tt.wantEmailH1)
+			}
+			if text := generatePlainTextEmail(articles, cfg.Location, cfg); !strings.HasPrefix(text, tt.wantEmailH1+"\n") {
+				t.Errorf("plain-text email starts %q, want %q", text[:min(len(text), 60)], tt.wantEmailH1)
+			}
+		})
+	}
+}
+
+func TestOverlappingRunsReportOnce(t *testing.T) {
+	inTempDir(t)
+	cfg := testConfig(t)
+	cfg.Source, cfg.StateFile = "file:articles.json", "state.json"
+
+	// a and b share the second the first run's cutoff ends on; b only shows up
+	// after that run
+	boundary := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	a := testArticle("a", boundary.Format(time.RFC3339))
+	b := testArticle("b", boundary.Format(time.RFC3339))
+	c := testArticle("c", boundary.Add(5*time.Minute).Format(time.RFC3339))
+
+	runs := []struct {
+		available []Article
+		want      string
+	}{
+		{[]Article{a}, "a"},
+		{[]Article{c, b, a}, "b,c"},
+		{[]Article{c, b, a}, ""},
+	}
+	for i, run := range runs {
+		data, err := json.Marshal(run.available)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, "articles.json", string(data))
+		os.RemoveAll("fetched_articles")
+
+		if code := runDigest(context.Background(), cfg, false); code != 0 {
+			t.Fatalf("run %d: runDigest() = %d, want 0", i+1, code)
+		}
+
+		var reported []string
+		files, _ := filepath.Glob(filepath.Join("fetched_articles", "leetcode_articles_*.txt"))
+		for _, file := range files {
+			uuids, err := readArticleUUIDs(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for uuid := range uuids {
+				reported = append(reported, uuid)
+			}
+		}
+		slices.Sort(reported)
+		if got := strings.Join(reported, ","); got != run.want {
+			t.Errorf("run %d reported %q, want %q", i+1, got, run.want)
+		}
+	}
+}
+
+func TestChunkWindowDeliveries(t *testing.T) {
+	tests := []struct {
+		window       string
+		wantSubjects []string
+	}{
+		{"", []string{"4 New Articles"}},
+		{"24h", []string{"1 New Articles", "1 New Articles", "2 New Articles"}},
+	}
+	for _, tt := range tests {
+		t.Run("window="+tt.window, func(t *testing.T) {
+			inTempDir(t)
+			t.Setenv("CHUNK_WINDOW", tt.window)
+			cfg := testConfig(t)
+			cfg.Source, cfg.StateFile = "file:articles.json", "state.json"
+			cfg.FromEmail, cfg.ToEmails = "bot@example.com", []string{"a@example.com"}
+			cfg.EmailProvider, cfg.SendGridAPIKey = providerSendGrid, "key"
+
+			// Three days since the last run, with articles on each of them
+			start := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Second)
+			if err := writeState(cfg.StateFile, State{LastProcessed: start}); err != nil {
+				t.Fatal(err)
+			}
+			var articles []Article
+			for i, offset := range []time.Duration{50, 49, 25, 1} {
+				articles = append(articles, testArticle(fmt.Sprintf("u%d", i), start.Add(offset*time.Hour).Format(time.RFC3339)))
+			}
+			data, err := json.Marshal(articles)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeFile(t, "articles.json", string(data))
+
+			// Each delivery should find the state advanced past the previous window
+			var subjects []string
+			var stateAtSend []time.Time
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload SendGridEmail
+				json.NewDecoder(r.Body).Decode(&payload)
+				_, count, _ := strings.Cut(payload.Subject, " - ")
+				subjects = append(subjects, count)
+				state, _ := readState(cfg.StateFile)
+				stateAtSend = append(stateAtSend, state.LastProcessed)
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer srv.Close()
+			cfg.HTTPClient = serverClient(srv)
+
+			if code := runDigest(context.Background(), cfg, true); code != 0 {
+				t.Fatalf("runDigest() = %d, want 0", code)
+			}
+			if got, want := strings.Join(subjects, "; "), strings.Join(tt.wantSubjects, "; "); got != want {
+				t.Errorf("deliveries %q, want %q", got, want)
+			}
+			for i := 1; i < len(stateAtSend); i++ {
+				if !stateAtSend[i].After(stateAtSend[i-1]) {
+					t.Errorf("state at delivery %d is %v, not advanced past %v", i+1, stateAtSend[i], stateAtSend[i-1])
+				}
+			}
+			if state, _ := readState(cfg.StateFile); !state.LastProcessed.Equal(start.Add(50 * time.Hour)) {
+				t.Errorf("final state %v, want the newest article's time", state.LastProcessed)
+			}
+		})
+	}
+}
+
+func TestMaxRuntimePartialDelivery(t *testing.T) {
+	inTempDir(t)
+	t.Setenv("MAX_RUNTIME", "1s")
+	cfg := testConfig(t)
+	cfg.StateFile = "state.json"
+
+	// The first page comes back at once, every later one hangs past MAX_RUNTIME
+	listing := listingArticles(3*batchSize, time.Now().Add(-time.Minute).UTC().Truncate(time.Second))
+	release := make(chan struct{})
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		if skip := int(body.Variables["skip"].(float64)); skip > 0 {
+			<-release
+			return
+		}
+		w.Write(listingJSON(len(listing), listing[:batchSize]))
+	})
+	defer close(release)
+	cfg.HTTPClient = serverClient(srv)
+
+	start := time.Now()
+	if code := runDigest(context.Background(), cfg, false); code != exitTimeLimited {
+		t.Fatalf("runDigest() = %d, want %d", code, exitTimeLimited)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("run took %s with MAX_RUNTIME=1s", elapsed)
+	}
+
+	// The first page is delivered
+	files, _ := filepath.Glob(filepath.Join("fetched_articles", "leetcode_articles_*.txt"))
+	if len(files) != 1 {
+		t.Fatalf("digest files %v, want one", files)
+	}
+	written, err := readArticleUUIDs(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != batchSize {
+		t.Errorf("wrote %d articles, want the %d fetched", len(written), batchSize)
+	}
+
+	// Older articles were never fetched, so the cutoff stays and the delivered
+	// ones are only recorded as seen
+	state, err := readState(cfg.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if since := time.Since(state.LastProcessed); since < firstRunWindow || since > firstRunWindow+time.Minute {
+		t.Errorf("state advanced to %v, want the first-run cutoff", state.LastProcessed)
+	}
+	if len(state.SeenUUIDs) != batchSize {
+		t.Errorf("state records %d seen articles, want %d", len(state.SeenUUIDs), batchSize)
+	}
+}
+
+func TestMinRunInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval string
+		args     []string
+		wantSkip bool
+	}{
+		{"within the interval", "1h", nil, true},
+		{"forced", "1h", []string{"-force"}, false},
+		{"interval passed", "1ns", nil, false},
+		{"no guard", "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			t.Setenv("SOURCE", "file:articles.json")
+			t.Setenv("STATE_FILE", "state.json")
+			t.Setenv("MIN_RUN_INTERVAL", tt.interval)
+			writeFile(t, "articles.json", "[]")
+
+			if code := cmdDigest(context.Background(), nil); code != 0 {
+				t.Fatalf("first run = %d, want 0", code)
+			}
+			var code int
+			out := captureStdout(t, func() { code = cmdDigest(context.Background(), tt.args) })
+			if code != 0 {
+				t.Fatalf("second run = %d, want 0", code)
+			}
+			skipped := strings.Contains(out, "within MIN_RUN_INTERVAL") && !strings.Contains(out, "Fetching articles")
+			if skipped != tt.wantSkip {
+				t.Errorf("second run skipped = %v, want %v:\n%s", skipped, tt.wantSkip, out)
+			}
+		})
+	}
+}
+
+func TestAlwaysShowLatest(t *testing.T) {
+	// The state reported a and b last time; c and d are new since
+	boundary := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	a := testArticle("a", boundary.Add(-time.Minute).Format(time.RFC3339))
+	b := testArticle("b", boundary.Format(time.RFC3339))
+	c := testArticle("c", boundary.Add(time.Minute).Format(time.RFC3339))
+	d := testArticle("d", boundary.Add(2*time.Minute).Format(time.RFC3339))
+
+	tests := []struct {
+		name      string
+		available []Article
+		latest    string
+		want      string // uuid:new or uuid:seen, in output order
+	}{
+		{"off", []Article{d, c, b, a}, "", "d:new,c:new"},
+		{"fills with seen articles", []Article{d, c, b, a}, "3", "d:new,c:new,b:seen"},
+		{"nothing new", []Article{b, a}, "3", "b:seen,a:seen"},
+		{"enough new articles", []Article{d, c, b, a}, "1", "d:new,c:new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			t.Setenv("ALWAYS_SHOW_LATEST", tt.latest)
+			t.Setenv("OUTPUT_FORMAT", "json")
+			cfg := testConfig(t)
+			cfg.Source, cfg.StateFile = "file:articles.json", "state.json"
+			if err := writeState(cfg.StateFile, State{LastProcessed: boundary, SeenUUIDs: []string{"a", "b"}}); err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(tt.available)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeFile(t, "articles.json", string(data))
+
+			if code := runDigest(context.Background(), cfg, false); code != 0 {
+				t.Fatalf("runDigest() = %d, want 0", code)
+			}
+
+			files, _ := filepath.Glob(filepath.Join("fetched_articles", "leetcode_articles_*.json"))
+			if len(files) != 1 {
+				t.Fatalf("digest files %v, want one", files)
+			}
+			written, err := readArticlesJSON(files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, article := range written {
+				flag := "new"
+				if article.AlreadySeen {
+					flag = "seen"
+				}
+				got = append(got, article.UUID+":"+flag)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("wrote %s, want %s", strings.Join(got, ","), tt.want)
+			}
+		})
+	}
+}
+
+func TestVerboseConsoleList(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantLine  string
+		wantCount bool
+	}{
+		{"default", nil, "1. Article u1\n", false},
+		{"verbose", []string{"-verbose"}, "1. Article u1 [2 tags, 7 reactions]\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			t.Setenv("SOURCE", "file:articles.json")
+			article := testArticle("u1", time.Now().Add(-time.Hour).UTC().Format(time.RFC3339))
+			article.Tags = []Tag{{Name: "Graph"}, {Name: "Array"}}
+			article.Reactions = []Reaction{{ReactionType: "UPVOTE", Count: 5}, {ReactionType: "AWESOME", Count: 2}}
+			data, err := json.Marshal([]Article{article})
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeFile(t, "articles.json", string(data))
+
+			cfg, err := loadCommandConfig("test", tt.args, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var code int
+			out := captureStdout(t, func() { code = runDigest(context.Background(), cfg, false) })
+			if code != 0 {
+				t.Fatalf("runDigest() = %d, want 0", code)
+			}
+			if !strings.Contains(out, tt.wantLine) {
+				t.Errorf("console list lacks %q:\n%s", tt.wantLine, out)
+			}
+			if got := strings.Contains(out, "reactions]"); got != tt.wantCount {
+				t.Errorf("counts shown = %v, want %v", got, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// discordMaxEmbeds is the most embeds Discord accepts in one webhook message
+const discordMaxEmbeds = 10
+
+// discordTitleMaxLen is the longest title Discord accepts in an embed
+const discordTitleMaxLen = 256
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url"`
+	Description string         `json:"description,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+}
+
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+// postToDiscord posts the digest to a Discord webhook with one embed per article,
+// split across several requests when there are more than fit in one. Between
+// requests it waits out the webhook's rate limit when the last one used it up.
+func postToDiscord(ctx context.Context, client Doer, webhookURL string, articles []Article, format articleFormat, cfg Config) error {
+	parts := (len(articles) + discordMaxEmbeds - 1) / discordMaxEmbeds
+	for part := 0; part < parts; part++ {
+		chunk := articles[part*discordMaxEmbeds : min((part+1)*discordMaxEmbeds, len(articles))]
+
+		var msg discordMessage
+		if part == 0 {
+			msg.Content = fmt.Sprintf("📚 **%s** - %d New Articles", emailTitle(cfg.RunLabel), len(newArticles(articles)))
+		}
+		for _, article := range chunk {
+			msg.Embeds = append(msg.Embeds, discordArticleEmbed(article, format, cfg))
+		}
+
+		wait, err := sendDiscordMessage(ctx, client, webhookURL, msg)
+		var discordErr *DiscordError
+		if errors.As(err, &discordErr) && discordErr.RateLimited() {
+			// Discord says how long to wait; try the same batch once more
+			if err = sleepContext(ctx, discordErr.RetryAfter); err == nil {
+				wait, err = sendDiscordMessage(ctx, client, webhookURL, msg)
+			}
+		}
+		if err != nil {
+			if part > 0 {
+				return fmt.Errorf("posted %d of %d messages: %w", part, parts, err)
+			}
+			return err
+		}
+
+		if wait > 0 && part < parts-1 {
+			if err := sleepContext(ctx, wait); err != nil {
+				return fmt.Errorf("posted %d of %d messages: %w", part+1, parts, err)
+			}
+		}
+	}
+	return nil
+}
+
+// discordArticleEmbed renders an article as an embed linking to it, with the
+// sections of the format and a footer naming the author and date
+func discordArticleEmbed(article Article, format articleFormat, cfg Config) discordEmbed {
+	footer := formatStringTimestamp(article.CreatedAt, cfg.DateFormat.DateTime, cfg.Location) + statusSuffix(article, cfg)
+	if author := authorName(article, cfg); author != "" {
+		footer = author + " · " + footer
+	}
+
+	return discordEmbed{
+		Title:       truncateText(displayTitle(article.Title, cfg.TitleMaxLen), discordTitleMaxLen),
+		URL:         articleURL(article),
+		Description: strings.Join(chatArticleLines(article, format, cfg), "\n"),
+		Footer:      &discordFooter{Text: footer},
+	}
+}
+
+// sendDiscordMessage posts one message to the webhook. When the request used up
+// the rate limit, it returns how long to wait before the next one.
+func sendDiscordMessage(ctx context.Context, client Doer, webhookURL string, msg discordMessage) (time.Duration, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal discord message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, &DiscordError{Err: fmt.Errorf("failed to send request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	resetAfter := discordResetAfter(resp.Header)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return 0, newDiscordError(resp.StatusCode, body, resetAfter)
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return resetAfter, nil
+	}
+	return 0, nil
+}
+
+// discordResetAfter reads the X-RateLimit-Reset-After header, given in seconds
+// with a fractional part
+func discordResetAfter(h http.Header) time.Duration {
+	seconds, err := strconv.ParseFloat(h.Get("X-RateLimit-Reset-After"), 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// newDiscordError builds a DiscordError from an error response, which looks like
+// {"message": "...", "code": 50006}
+func newDiscordError(status int, body []byte, retryAfter time.Duration) *DiscordError {
+	var parsed struct {
+		Message string `json:"message"`
+	}
+
+	discordErr := &DiscordError{StatusCode: status, RetryAfter: retryAfter}
+	if json.Unmarshal(body, &parsed) == nil && parsed.Message != "" {
+		discordErr.Message = parsed.Message
+	} else {
+		discordErr.Message = strings.TrimSpace(string(body))
+	}
+	return discordErr
+}
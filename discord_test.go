@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPostToDiscord(t *testing.T) {
+	var messages []discordMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg discordMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("payload is not JSON: %v", err)
+		}
+		messages = append(messages, msg)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(t)
+	articles := listingArticles(23, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	articles[0].Summary = "Priority queues & more."
+	if err := postToDiscord(context.Background(), srv.Client(), srv.URL, articles, cfg.DiscordFormat, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	var embeds []string
+	for _, msg := range messages {
+		embeds = append(embeds, fmt.Sprint(len(msg.Embeds)))
+	}
+	if got := strings.Join(embeds, ","); got != "10,10,3" {
+		t.Fatalf("embeds per message %s, want 10,10,3", got)
+	}
+	if want := "📚 **LeetCode Daily Digest** - 23 New Articles"; messages[0].Content != want || messages[1].Content != "" {
+		t.Errorf("contents %q, %q, want %q on the first message only", messages[0].Content, messages[1].Content, want)
+	}
+
+	want := discordEmbed{
+		Title:       "Article u000",
+		URL:         articleURL(articles[0]),
+		Description: "Priority queues & more.",
+		Footer:      &discordFooter{Text: "author-u000 · 2024-05-01 15:30:00 IST"},
+	}
+	got, _ := json.Marshal(messages[0].Embeds[0])
+	if wantJSON, _ := json.Marshal(want); string(got) != string(wantJSON) {
+		t.Errorf("first embed %s, want %s", got, wantJSON)
+	}
+}
+
+func TestDiscordRateLimit(t *testing.T) {
+	tests := []struct {
+		name         string
+		respond      func(w http.ResponseWriter, request int)
+		wantRequests int
+	}{
+		{
+			name: "limit used up",
+			respond: func(w http.ResponseWriter, request int) {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset-After", "0.2")
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantRequests: 2,
+		},
+		{
+			name: "429 retried",
+			respond: func(w http.ResponseWriter, request int) {
+				if request == 1 {
+					w.Header().Set("X-RateLimit-Reset-After", "0.2")
+					http.Error(w, `{"message": "You are being rate limited.", "retry_after": 0.2}`, http.StatusTooManyRequests)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantRequests: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var times []time.Time
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				times = append(times, time.Now())
+				n := len(times)
+				mu.Unlock()
+				tt.respond(w, n)
+			}))
+			defer srv.Close()
+
+			cfg := testConfig(t)
+			articles := listingArticles(discordMaxEmbeds+1, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+			if err := postToDiscord(context.Background(), srv.Client(), srv.URL, articles, cfg.DiscordFormat, cfg); err != nil {
+				t.Fatal(err)
+			}
+			if len(times) != tt.wantRequests {
+				t.Fatalf("made %d requests, want %d", len(times), tt.wantRequests)
+			}
+			if gap := times[1].Sub(times[0]); gap < 200*time.Millisecond {
+				t.Errorf("second request %s after the first, want the 200ms reset waited out", gap)
+			}
+		})
+	}
+}
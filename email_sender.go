@@ -2,26 +2,82 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 const sendGridAPIURL = "https://api.sendgrid.com/v3/mail/send"
 
+// sendGridMaxRecipients is SendGrid's limit on recipients per request
+const sendGridMaxRecipients = 1000
+
+// Email layouts
+const (
+	layoutFull    = "full"
+	layoutCompact = "compact" // title and meta line only
+)
+
+// EmailSender delivers a rendered email through one provider. Rejected recipients
+// are reported as a *RecipientError; the message still reaches the others.
+type EmailSender interface {
+	Send(ctx context.Context, msg emailMessage, to []string) error
+}
+
+// SendGridSender sends email through the SendGrid v3 API
+type SendGridSender struct {
+	APIKey    string
+	BatchSize int  // recipients per request, at most sendGridMaxRecipients
+	Client    Doer // nil means defaultHTTPClient
+}
+
+func (s SendGridSender) Send(ctx context.Context, msg emailMessage, to []string) error {
+	return sendEmailViaSendGrid(ctx, httpClient(s.Client), s.APIKey, msg, to, s.BatchSize)
+}
+
+// emailMessage is a rendered email ready to hand to a provider
+type emailMessage struct {
+	FromEmail   string
+	FromName    string
+	Subject     string
+	Text        string // plain-text alternative to HTML
+	HTML        string
+	Attachments []emailAttachment
+
+	// TemplateID selects a stored SendGrid dynamic template, which is rendered
+	// from TemplateData instead of sending HTML
+	TemplateID   string
+	TemplateData interface{}
+}
+
+// emailAttachment is a file attached to an email
+type emailAttachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
 // SendGridEmail represents the email structure for SendGrid API
 type SendGridEmail struct {
-	Personalizations []Personalization `json:"personalizations"`
-	From             EmailAddress      `json:"from"`
-	Subject          string            `json:"subject"`
-	Content          []Content         `json:"content"`
+	Personalizations []Personalization    `json:"personalizations"`
+	From             EmailAddress         `json:"from"`
+	Subject          string               `json:"subject"`
+	Content          []Content            `json:"content,omitempty"`
+	Attachments      []SendGridAttachment `json:"attachments,omitempty"`
+	TemplateID       string               `json:"template_id,omitempty"`
 }
 
 type Personalization struct {
-	To []EmailAddress `json:"to"`
+	To                  []EmailAddress `json:"to"`
+	DynamicTemplateData interface{}    `json:"dynamic_template_data,omitempty"`
 }
 
 type EmailAddress struct {
@@ -34,8 +90,55 @@ type Content struct {
 	Value string `json:"value"`
 }
 
-// sendEmailViaSendGrid sends an email using SendGrid API
-func sendEmailViaSendGrid(apiKey, fromEmail, fromName string, toEmails []string, subject, htmlContent string) error {
+// SendGridAttachment is a base64-encoded file attached to a SendGrid email
+type SendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+}
+
+// sendEmailViaSendGrid sends an email using SendGrid API. Recipients are split into
+// requests of at most batchSize, staying under SendGrid's per-request limit; failed
+// batches are reported as a *RecipientError. When every batch fails, the first
+// batch's error is returned as-is, just as for a single request.
+func sendEmailViaSendGrid(ctx context.Context, client Doer, apiKey string, msg emailMessage, toEmails []string, batchSize int) error {
+	if batchSize <= 0 || batchSize > sendGridMaxRecipients {
+		batchSize = sendGridMaxRecipients
+	}
+	if len(toEmails) <= batchSize {
+		return postSendGridEmail(ctx, client, apiKey, msg, toEmails)
+	}
+
+	result := &RecipientError{Rejected: make(map[string]error)}
+	var firstErr error
+	for start := 0; start < len(toEmails); start += batchSize {
+		end := min(start+batchSize, len(toEmails))
+		batch := toEmails[start:end]
+
+		if err := postSendGridEmail(ctx, client, apiKey, msg, batch); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			for _, email := range batch {
+				result.Rejected[email] = err
+			}
+			continue
+		}
+		result.Accepted = append(result.Accepted, batch...)
+	}
+
+	switch {
+	case len(result.Rejected) == 0:
+		return nil
+	case result.AllRejected():
+		return firstErr
+	}
+	return result
+}
+
+// postSendGridEmail sends a single SendGrid request to the given recipients
+func postSendGridEmail(ctx context.Context, client Doer, apiKey string, msg emailMessage, toEmails []string) error {
 	// Build recipient list
 	var recipients []EmailAddress
 	for _, email := range toEmails {
@@ -48,16 +151,37 @@ func sendEmailViaSendGrid(apiKey, fromEmail, fromName string, toEmails []string,
 			{To: recipients},
 		},
 		From: EmailAddress{
-			Email: fromEmail,
-			Name:  fromName,
+			Email: msg.FromEmail,
+			Name:  msg.FromName,
 		},
-		Subject: subject,
-		Content: []Content{
+		Subject: msg.Subject,
+	}
+
+	if msg.TemplateID != "" {
+		// The stored template renders the body, so no content is sent
+		emailPayload.TemplateID = msg.TemplateID
+		emailPayload.Personalizations[0].DynamicTemplateData = msg.TemplateData
+	} else {
+		// SendGrid requires text/plain to come before text/html
+		emailPayload.Content = []Content{
+			{
+				Type:  "text/plain",
+				Value: msg.Text,
+			},
 			{
 				Type:  "text/html",
-				Value: htmlContent,
+				Value: msg.HTML,
 			},
-		},
+		}
+	}
+
+	for _, a := range msg.Attachments {
+		emailPayload.Attachments = append(emailPayload.Attachments, SendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(a.Content),
+			Type:        a.ContentType,
+			Filename:    a.Filename,
+			Disposition: "attachment",
+		})
 	}
 
 	jsonData, err := json.Marshal(emailPayload)
@@ -66,7 +190,7 @@ func sendEmailViaSendGrid(apiKey, fromEmail, fromName string, toEmails []string,
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", sendGridAPIURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", sendGridAPIURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -75,26 +199,127 @@ func sendEmailViaSendGrid(apiKey, fromEmail, fromName string, toEmails []string,
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send request
-	client := &http.Client{Timeout: 15 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return &EmailError{Provider: providerSendGrid, Err: fmt.Errorf("failed to send request: %w", err)}
 	}
 	defer resp.Body.Close()
 
 	// Check response
 	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("sendgrid API returned status %d: %s", resp.StatusCode, string(body))
+		return newSendGridError(resp.StatusCode, body)
 	}
 
 	return nil
 }
 
-// generateHTMLEmail creates an HTML email from articles
-func generateHTMLEmail(articles []Article, ist *time.Location) string {
+// newSendGridError builds an EmailError from a SendGrid error response, which
+// looks like {"errors": [{"message": "...", "field": "..."}]}
+func newSendGridError(status int, body []byte) *EmailError {
+	var parsed struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Field   string `json:"field"`
+		} `json:"errors"`
+	}
+
+	emailErr := &EmailError{Provider: providerSendGrid, StatusCode: status}
+	if json.Unmarshal(body, &parsed) == nil {
+		for _, e := range parsed.Errors {
+			msg := e.Message
+			if e.Field != "" {
+				msg = fmt.Sprintf("%s (field %s)", e.Message, e.Field)
+			}
+			emailErr.Messages = append(emailErr.Messages, msg)
+		}
+	}
+	if len(emailErr.Messages) == 0 && len(body) > 0 {
+		emailErr.Messages = []string{strings.TrimSpace(string(body))}
+	}
+	return emailErr
+}
+
+// overflowAttachmentName is the attachment holding every article when the email is trimmed
+const overflowAttachmentName = "articles.json"
+
+// overflowAttachments returns the JSON attachment with the full article list when
+// MAX_EMAIL_ARTICLES trims the email body, and nothing otherwise
+func overflowAttachments(articles []Article, cfg Config) ([]emailAttachment, error) {
+	if cfg.MaxEmailArticles <= 0 || len(articles) <= cfg.MaxEmailArticles {
+		return nil, nil
+	}
+
+	data, err := json.MarshalIndent(exportArticles(articles, cfg), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode article attachment: %w", err)
+	}
+	return []emailAttachment{{
+		Filename:    overflowAttachmentName,
+		ContentType: "application/json",
+		Content:     data,
+	}}, nil
+}
+
+// templateArticle is an article as exposed to SendGrid dynamic templates
+type templateArticle struct {
+	Title     string   `json:"title"`
+	URL       string   `json:"url"`
+	Author    string   `json:"author"`
+	Posted    string   `json:"posted"`
+	Summary   string   `json:"summary"`
+	Tags      []string `json:"tags"`
+	Reactions []string `json:"reactions"`
+}
+
+// digestTemplateData is the dynamic_template_data sent in SendGrid template mode
+type digestTemplateData struct {
+	Subject  string            `json:"subject"`
+	Count    int               `json:"count"`
+	Articles []templateArticle `json:"articles"`
+}
+
+// buildTemplateData converts articles into dynamic template data, formatted the
+// same way as the HTML email
+func buildTemplateData(subject string, articles []Article, cfg Config) digestTemplateData {
+	data := digestTemplateData{Subject: subject, Count: len(articles), Articles: []templateArticle{}}
+	if cfg.MaxEmailArticles > 0 && len(articles) > cfg.MaxEmailArticles {
+		articles = articles[:cfg.MaxEmailArticles]
+	}
+
+	for _, article := range articles {
+		ta := templateArticle{
+			Title:     displayTitle(article.Title, cfg.TitleMaxLen),
+			URL:       articleURL(article),
+			Author:    authorName(article, cfg),
+			Posted:    formatStringTimestamp(article.CreatedAt, cfg.DateFormat.DateTime, cfg.Location),
+			Summary:   truncateText(plainSummary(article.Summary, cfg.HTMLStripMode), cfg.EmailFormat.SummaryLen),
+			Tags:      []string{},
+			Reactions: []string{},
+		}
+		tags, _ := visibleTags(article.Tags, cfg.MaxTagsShown)
+		for _, tag := range tags {
+			ta.Tags = append(ta.Tags, tag.Name)
+		}
+		for _, reaction := range mergeReactions(article.Reactions) {
+			ta.Reactions = append(ta.Reactions, formatReaction(cfg.ReactionFormat, reaction))
+		}
+		data.Articles = append(data.Articles, ta)
+	}
+	return data
+}
+
+// generateHTMLEmail creates an HTML email from articles. With MAX_EMAIL_ARTICLES
+// only the first articles are rendered and a note points to the attachment.
+func generateHTMLEmail(articles []Article, loc *time.Location, cfg Config) string {
 	var html strings.Builder
 
+	total, newCount := len(articles), len(newArticles(articles))
+	runID := digestID(articles)
+	if cfg.MaxEmailArticles > 0 && total > cfg.MaxEmailArticles {
+		articles = articles[:cfg.MaxEmailArticles]
+	}
+
 	html.WriteString(`
 <!DOCTYPE html>
 <html>
@@ -105,6 +330,12 @@ func generateHTMLEmail(articles []Article, ist *time.Location) string {
         .subtitle { color: #666; font-size: 14px; margin-bottom: 40px; }
         .article { margin-bottom: 40px; padding-bottom: 30px; border-bottom: 1px solid #e5e5e5; }
         .article:last-child { border-bottom: none; }
+        .article.compact { margin-bottom: 16px; padding-bottom: 12px; }
+        .article.compact .article-title { font-size: 16px; margin-bottom: 4px; }
+        .article.compact .article-meta { margin-bottom: 0; }
+        .section-heading { font-size: 13px; text-transform: uppercase; letter-spacing: 1px; color: #999; margin: 0 0 20px; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Arial, sans-serif; }
+        .article-thumbnail { display: block; max-width: 160px; height: auto; margin-bottom: 12px; border-radius: 4px; }
+        .article-rank { font-size: 12px; font-weight: 600; color: #b45309; margin-bottom: 4px; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Arial, sans-serif; }
         .article-title { font-size: 20px; font-weight: 600; margin-bottom: 8px; line-height: 1.4; }
         .article-title a { color: #222; text-decoration: none; }
         .article-title a:hover { color: #0066cc; }
@@ -113,56 +344,286 @@ func generateHTMLEmail(articles []Article, ist *time.Location) string {
         .article-tags { margin-top: 12px; }
         .tag { display: inline; color: #666; font-size: 13px; margin-right: 12px; }
         .tag:before { content: "#"; color: #999; }
+        .tag-featured { color: #b45309; background: #fef3c7; font-weight: 600; padding: 1px 6px; border-radius: 3px; }
+        .tag-more { display: inline; color: #999; font-size: 12px; }
+        .article-reactions { margin-top: 8px; }
+        .reaction { display: inline; color: #888; font-size: 12px; margin-right: 12px; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Arial, sans-serif; }
+        .article-cta { margin-top: 10px; font-size: 13px; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Arial, sans-serif; }
+        .article-cta a { color: #0066cc; text-decoration: none; }
+        .overflow-note { font-size: 14px; color: #666; font-style: italic; margin-bottom: 20px; }
         .footer { text-align: center; margin-top: 50px; padding-top: 20px; border-top: 1px solid #e5e5e5; color: #999; font-size: 12px; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Arial, sans-serif; }
     </style>
 </head>
 <body>
-    <h1>LeetCode Daily Digest</h1>
-    <div class="subtitle">` + fmt.Sprintf("%d new articles • %s", len(articles), time.Now().In(ist).Format("January 2, 2006")) + `</div>
+    <h1>` + escapeHTML(emailTitle(cfg.RunLabel)) + `</h1>
+    <div class="subtitle">` + fmt.Sprintf("%d new articles%s • %s", newCount, showingNote(total, cfg), time.Now().In(loc).Format(cfg.DateFormat.Date)) + `</div>
 `)
 
-	for _, article := range articles {
-		html.WriteString(fmt.Sprintf(`
-    <div class="article">
-        <div class="article-title"><a href="https://leetcode.com/discuss/post/%d/%s/">%s</a></div>
-        <div class="article-meta">By %s • %s</div>`,
-			article.TopicId,
-			article.Slug,
-			escapeHTML(article.Title),
-			escapeHTML(article.Author.UserName),
-			formatStringTimestamp(article.CreatedAt),
-		))
-
-		if article.Summary != "" {
-			html.WriteString(fmt.Sprintf(`
-        <div class="article-summary">%s</div>`,
-				escapeHTML(truncateText(article.Summary, 250)),
-			))
+	if cfg.FeatureTopN > 0 {
+		// Featured articles are pulled out of order, so they keep the rank
+		// of their position in the list
+		featured, rest := splitFeatured(articles, cfg.FeatureTopN)
+		html.WriteString(`
+    <div class="section-heading">Featured</div>`)
+		for _, i := range featured {
+			writeArticleCard(&html, articles[i], i+1, cfg, false)
 		}
-
-		if len(article.Tags) > 0 {
+		if len(rest) > 0 {
 			html.WriteString(`
-        <div class="article-tags">`)
-			for _, tag := range article.Tags {
-				html.WriteString(fmt.Sprintf(`<span class="tag">%s</span>`, escapeHTML(tag.Name)))
-			}
-			html.WriteString(`</div>`)
+    <div class="section-heading">More articles</div>`)
+		}
+		for _, i := range rest {
+			writeArticleCard(&html, articles[i], i+1, cfg, true)
 		}
+	} else {
+		for i, article := range articles {
+			writeArticleCard(&html, article, i+1, cfg, cfg.EmailFormat.Layout == layoutCompact)
+		}
+	}
 
-		html.WriteString(`
-    </div>`)
+	if len(articles) < total {
+		html.WriteString(fmt.Sprintf(`
+    <div class="overflow-note">Showing %d of %d articles. The full list is attached as %s.</div>`,
+			len(articles), total, overflowAttachmentName))
 	}
 
 	html.WriteString(`
     <div class="footer">
         <p>Automated digest • LeetCode Articles Fetcher</p>
-    </div>
+    </div>`)
+
+	if cfg.TrackingPixelURL != "" {
+		html.WriteString(fmt.Sprintf(`
+    <img src="%s" width="1" height="1" alt="" style="display:block;border:0;">`,
+			escapeHTML(trackingPixelURL(cfg.TrackingPixelURL, runID))))
+	}
+
+	html.WriteString(`
 </body>
 </html>`)
 
 	return html.String()
 }
 
+// generatePlainTextEmail creates the plain-text alternative to generateHTMLEmail,
+// listing each article's number, title, URL, author, date and summary
+func generatePlainTextEmail(articles []Article, loc *time.Location, cfg Config) string {
+	var text strings.Builder
+
+	total, newCount := len(articles), len(newArticles(articles))
+	if cfg.MaxEmailArticles > 0 && total > cfg.MaxEmailArticles {
+		articles = articles[:cfg.MaxEmailArticles]
+	}
+
+	fmt.Fprintf(&text, "%s\n", emailTitle(cfg.RunLabel))
+	fmt.Fprintf(&text, "%d new articles%s • %s\n", newCount, showingNote(total, cfg), time.Now().In(loc).Format(cfg.DateFormat.Date))
+
+	for i, article := range articles {
+		fmt.Fprintf(&text, "\n%d. %s\n", i+1, displayTitle(article.Title, cfg.TitleMaxLen))
+		fmt.Fprintf(&text, "   %s\n", articleURL(article))
+		fmt.Fprintf(&text, "   By %s • %s%s%s\n", authorName(article, cfg),
+			formatStringTimestamp(article.CreatedAt, cfg.DateFormat.DateTime, cfg.Location), engagementSuffix(article), statusSuffix(article, cfg))
+		if summary := plainSummary(article.Summary, cfg.HTMLStripMode); summary != "" {
+			fmt.Fprintf(&text, "   %s\n", truncateText(summary, cfg.EmailFormat.SummaryLen))
+		}
+	}
+
+	if len(articles) < total {
+		fmt.Fprintf(&text, "\nShowing %d of %d articles. The full list is attached as %s.\n",
+			len(articles), total, overflowAttachmentName)
+	}
+
+	text.WriteString("\n--\nAutomated digest • LeetCode Articles Fetcher\n")
+	return text.String()
+}
+
+// writeArticleCard renders one article, rank being its position in the digest.
+// Compact cards show only the title and meta line.
+func writeArticleCard(html *strings.Builder, article Article, rank int, cfg Config, compact bool) {
+	class := "article"
+	if compact {
+		class = "article compact"
+	}
+	html.WriteString(fmt.Sprintf(`
+    <div class="%s">`, class))
+
+	if cfg.ShowThumbnails && !compact {
+		if src, ok := safeImageURL(article.Thumbnail); ok {
+			html.WriteString(fmt.Sprintf(`
+        <img class="article-thumbnail" src="%s" alt="" width="160">`, escapeHTML(src)))
+		}
+	}
+
+	if label := rankLabel(rank, article, cfg); label != "" {
+		html.WriteString(fmt.Sprintf(`
+        <div class="article-rank">%s</div>`, escapeHTML(label)))
+	}
+
+	html.WriteString(fmt.Sprintf(`
+        <div class="article-title"><a href="%s">%s</a></div>
+        <div class="article-meta">By %s • %s%s%s</div>`,
+		escapeHTML(articleURL(article)),
+		escapeHTML(displayTitle(article.Title, cfg.TitleMaxLen)),
+		escapeHTML(authorName(article, cfg)),
+		escapeHTML(formatStringTimestamp(article.CreatedAt, cfg.DateFormat.DateTime, cfg.Location)),
+		engagementSuffix(article),
+		statusSuffix(article, cfg),
+	))
+
+	if !compact {
+		for _, block := range cfg.EmailFormat.Sections {
+			cardBlocks[block](html, article, cfg)
+		}
+	}
+
+	if cfg.ShowDiscussionCTA {
+		// Comments live at the bottom of the post page, which has no stable anchor
+		html.WriteString(fmt.Sprintf(`
+        <div class="article-cta"><a href="%s">💬 Join the discussion</a></div>`, escapeHTML(articleURL(article))))
+	}
+
+	html.WriteString(`
+    </div>`)
+}
+
+// cardBlocks renders the optional blocks of a full card, keyed by CARD_LAYOUT token
+var cardBlocks = map[string]func(html *strings.Builder, article Article, cfg Config){
+	"summary":   writeCardSummary,
+	"tags":      writeCardTags,
+	"reactions": writeCardReactions,
+}
+
+// defaultCardLayout is the original block order of a full card
+var defaultCardLayout = []string{"summary", "tags", "reactions"}
+
+// parseCardLayout validates a CARD_LAYOUT list. Blocks may be left out but not repeated.
+func parseCardLayout(tokens []string) ([]string, error) {
+	if len(tokens) == 0 {
+		return defaultCardLayout, nil
+	}
+
+	seen := make(map[string]bool, len(tokens))
+	layout := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		token = strings.ToLower(token)
+		if _, ok := cardBlocks[token]; !ok {
+			return nil, fmt.Errorf("unknown block %q: must be summary, tags or reactions", token)
+		}
+		if seen[token] {
+			return nil, fmt.Errorf("block %q is listed more than once", token)
+		}
+		seen[token] = true
+		layout = append(layout, token)
+	}
+	return layout, nil
+}
+
+// writeCardSummary renders the truncated summary of a card
+func writeCardSummary(html *strings.Builder, article Article, cfg Config) {
+	summary := plainSummary(article.Summary, cfg.HTMLStripMode)
+	if summary == "" {
+		return
+	}
+	html.WriteString(fmt.Sprintf(`
+        <div class="article-summary">%s</div>`,
+		escapeHTML(truncateText(summary, cfg.EmailFormat.SummaryLen)),
+	))
+}
+
+// writeCardTags renders the tag chips of a card
+func writeCardTags(html *strings.Builder, article Article, cfg Config) {
+	if len(article.Tags) == 0 {
+		return
+	}
+	html.WriteString(`
+        <div class="article-tags">`)
+	tags, hidden := visibleTags(article.Tags, cfg.MaxTagsShown)
+	for _, tag := range tags {
+		class := "tag"
+		if cfg.FeaturedTags[strings.ToLower(tag.Slug)] {
+			class = "tag tag-featured"
+		}
+		html.WriteString(fmt.Sprintf(`<span class="%s">%s</span>`, class, escapeHTML(tag.Name)))
+	}
+	if hidden > 0 {
+		html.WriteString(fmt.Sprintf(`<span class="tag-more">+%d more</span>`, hidden))
+	}
+	html.WriteString(`</div>`)
+}
+
+// writeCardReactions renders the reaction counts of a card
+func writeCardReactions(html *strings.Builder, article Article, cfg Config) {
+	if len(article.Reactions) == 0 {
+		return
+	}
+	html.WriteString(`
+        <div class="article-reactions">`)
+	for _, reaction := range mergeReactions(article.Reactions) {
+		html.WriteString(fmt.Sprintf(`<span class="reaction">%s</span>`, escapeHTML(formatReaction(cfg.ReactionFormat, reaction))))
+	}
+	html.WriteString(`</div>`)
+}
+
+// trackingPixelURL adds the run ID to the pixel URL as the "run" query parameter
+func trackingPixelURL(base, runID string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base // Validated when the configuration is loaded
+	}
+	q := u.Query()
+	q.Set("run", runID)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// emailTitle is the email heading, naming the run label when there is one
+func emailTitle(label string) string {
+	if label == "" {
+		return "LeetCode Daily Digest"
+	}
+	return "LeetCode Discuss — " + label + " digest"
+}
+
+// splitFeatured picks the n most reacted articles, most reacted first, and returns
+// their indexes along with those of the remaining articles in their original order
+func splitFeatured(articles []Article, n int) (featured, rest []int) {
+	order := make([]int, len(articles))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return totalReactions(articles[order[i]]) > totalReactions(articles[order[j]])
+	})
+
+	if n > len(order) {
+		n = len(order)
+	}
+	featured = order[:n]
+	isFeatured := make(map[int]bool, n)
+	for _, idx := range featured {
+		isFeatured[idx] = true
+	}
+	for i := range articles {
+		if !isFeatured[i] {
+			rest = append(rest, i)
+		}
+	}
+	return featured, rest
+}
+
+// safeImageURL accepts only absolute http(s) URLs for use in an img src attribute
+func safeImageURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return u.String(), true
+}
+
 // escapeHTML escapes special HTML characters
 func escapeHTML(s string) string {
 	s = strings.ReplaceAll(s, "&", "&amp;")
@@ -173,10 +634,50 @@ func escapeHTML(s string) string {
 	return s
 }
 
-// truncateText truncates text to specified length with ellipsis
+// displayTitle shortens a title to maxLen runes for display; 0 keeps the full title
+func displayTitle(title string, maxLen int) string {
+	if maxLen <= 0 {
+		return title
+	}
+	return truncateText(title, maxLen)
+}
+
+// articleStatus labels an article "New" or "Already seen" when ALWAYS_SHOW_LATEST
+// mixes both into the digest; it is empty otherwise
+func articleStatus(article Article, cfg Config) string {
+	switch {
+	case cfg.AlwaysShowLatest == 0:
+		return ""
+	case article.AlreadySeen:
+		return "Already seen"
+	default:
+		return "New"
+	}
+}
+
+// statusSuffix appends the articleStatus to a meta line
+func statusSuffix(article Article, cfg Config) string {
+	if status := articleStatus(article, cfg); status != "" {
+		return " • " + status
+	}
+	return ""
+}
+
+// engagementSuffix appends the non-zero view and comment counts to a meta line
+func engagementSuffix(article Article) string {
+	var suffix string
+	for _, part := range engagementParts(article) {
+		suffix += " • " + part
+	}
+	return suffix
+}
+
+// truncateText truncates text to maxLen runes with ellipsis, never splitting a
+// multi-byte character
 func truncateText(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	if utf8.RuneCountInString(s) <= maxLen {
 		return s
 	}
-	return s[:maxLen] + "..."
+	runes := []rune(s)
+	return string(runes[:maxLen]) + "..."
 }
@@ -0,0 +1,621 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestTitleMaxLen(t *testing.T) {
+	t.Setenv("TITLE_MAX_LEN", "20")
+	cfg := testConfig(t)
+	dir := t.TempDir()
+
+	long := "Dynamic programming on trees: a very long walkthrough"
+	article := testArticle("u1", "2024-05-01T10:00:00Z")
+	article.Title = long
+	articles := []Article{article}
+
+	short := "Dynamic programming ..."
+	if got := displayTitle(long, cfg.TitleMaxLen); got != short {
+		t.Fatalf("displayTitle = %q, want %q", got, short)
+	}
+	if html := generateHTMLEmail(articles, cfg.Location, cfg); !strings.Contains(html, ">"+short+"</a>") || strings.Contains(html, long) {
+		t.Errorf("HTML email does not show the shortened title only")
+	}
+	if text := generatePlainTextEmail(articles, cfg.Location, cfg); !strings.Contains(text, "1. "+short+"\n") || strings.Contains(text, long) {
+		t.Errorf("plain-text email does not show the shortened title only:\n%s", text)
+	}
+
+	// Files keep the full title
+	textFile := filepath.Join(dir, "digest.txt")
+	if err := writeArticlesToFile(articles, textFile, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, textFile); !strings.Contains(content, "Title: "+long+"\n") {
+		t.Errorf("text file does not keep the full title:\n%s", content)
+	}
+	jsonFile := filepath.Join(dir, "digest.json")
+	if err := writeArticlesToJSON(articles, jsonFile, cfg); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := readArticlesJSON(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved[0].Title != long {
+		t.Errorf("JSON title = %q, want the full title", saved[0].Title)
+	}
+}
+
+func TestThumbnails(t *testing.T) {
+	t.Setenv("SHOW_THUMBNAILS", "true")
+	cfg := testConfig(t)
+
+	tests := []struct {
+		thumbnail string
+		want      string // the img tag expected, empty for none
+	}{
+		{"https://assets.leetcode.com/thumb.png", `<img class="article-thumbnail" src="https://assets.leetcode.com/thumb.png"`},
+		{"", ""},
+		{"javascript:alert(1)", ""},
+		{"/relative/thumb.png", ""},
+	}
+	for _, tt := range tests {
+		article := testArticle("u1", "2024-05-01T10:00:00Z")
+		article.Thumbnail = tt.thumbnail
+		html := generateHTMLEmail([]Article{article}, cfg.Location, cfg)
+
+		if tt.want == "" {
+			if strings.Contains(html, "article-thumbnail\" src") {
+				t.Errorf("thumbnail %q: image rendered, want the text-only card", tt.thumbnail)
+			}
+		} else if !strings.Contains(html, tt.want) {
+			t.Errorf("thumbnail %q: image not rendered", tt.thumbnail)
+		}
+		if !strings.Contains(html, `<div class="article-title">`) {
+			t.Errorf("thumbnail %q: card has no title", tt.thumbnail)
+		}
+	}
+}
+
+func TestFeatureTopN(t *testing.T) {
+	t.Setenv("FEATURE_TOP_N", "2")
+	cfg := testConfig(t)
+
+	var articles []Article
+	for i, reactions := range []int{3, 40, 1, 25} {
+		article := testArticle(fmt.Sprintf("u%d", i), "2024-05-01T10:00:00Z")
+		article.Summary = fmt.Sprintf("Summary of u%d", i)
+		article.Reactions = []Reaction{{Count: reactions, ReactionType: "UPVOTE"}}
+		articles = append(articles, article)
+	}
+	html := generateHTMLEmail(articles, cfg.Location, cfg)
+
+	// u1 and u3 are the most reacted, so only they are rendered with summaries
+	for uuid, featured := range map[string]bool{"u0": false, "u1": true, "u2": false, "u3": true} {
+		if got := strings.Contains(html, "Summary of "+uuid); got != featured {
+			t.Errorf("summary of %s shown = %v, want %v", uuid, got, featured)
+		}
+	}
+	if strings.Index(html, "Article u1") > strings.Index(html, "Article u3") {
+		t.Error("featured articles are not ordered by reactions")
+	}
+	if n := strings.Count(html, `class="article compact"`); n != 2 {
+		t.Errorf("%d compact cards, want 2", n)
+	}
+}
+
+func TestFeaturedRanksWithoutUUID(t *testing.T) {
+	t.Setenv("FEATURE_TOP_N", "1")
+	t.Setenv("SORT_BY", "reactions")
+	cfg := testConfig(t)
+
+	// Articles without a UUID still each keep the rank of their position
+	var articles []Article
+	for i, reactions := range []int{30, 20, 10} {
+		article := testArticle(fmt.Sprintf("u%d", i), "2024-05-01T10:00:00Z")
+		article.UUID = ""
+		article.Reactions = []Reaction{{Count: reactions, ReactionType: "UPVOTE"}}
+		articles = append(articles, article)
+	}
+	html := generateHTMLEmail(articles, cfg.Location, cfg)
+
+	for i, want := range []string{"#1 (30 reactions)", "#2 (20 reactions)", "#3 (10 reactions)"} {
+		if !strings.Contains(html, `<div class="article-rank">`+want+`</div>`) {
+			t.Errorf("card %d lacks rank %q", i+1, want)
+		}
+	}
+}
+
+func TestUnparsedTimestampIsEscaped(t *testing.T) {
+	cfg := testConfig(t)
+	article := testArticle("u1", `<img src=x onerror="alert(1)">`)
+	html := generateHTMLEmail([]Article{article}, cfg.Location, cfg)
+
+	if strings.Contains(html, "<img src=x") {
+		t.Error("raw createdAt was injected into the HTML email")
+	}
+	if !strings.Contains(html, "&lt;img src=x onerror=&quot;alert(1)&quot;&gt;") {
+		t.Error("escaped createdAt missing from the meta line")
+	}
+}
+
+func TestDiscussionCTA(t *testing.T) {
+	articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z"), testArticle("u2", "2024-05-01T09:00:00Z")}
+	tests := []struct {
+		env  string
+		want int
+	}{
+		{"", 0},
+		{"false", 0},
+		{"true", 2},
+	}
+	for _, tt := range tests {
+		t.Setenv("SHOW_DISCUSSION_CTA", tt.env)
+		cfg := testConfig(t)
+		html := generateHTMLEmail(articles, cfg.Location, cfg)
+
+		if got := strings.Count(html, "Join the discussion"); got != tt.want {
+			t.Errorf("SHOW_DISCUSSION_CTA=%q: %d CTAs, want %d", tt.env, got, tt.want)
+		}
+		if tt.want > 0 && !strings.Contains(html, `<a href="`+articleURL(articles[0])+`">💬 Join the discussion</a>`) {
+			t.Errorf("CTA does not link to the article")
+		}
+	}
+}
+
+func TestSendGridBatchesRecipients(t *testing.T) {
+	recipients := make([]string, 1500)
+	for i := range recipients {
+		recipients[i] = fmt.Sprintf("user%d@example.com", i)
+	}
+
+	tests := []struct {
+		batchSize int
+		want      []int
+	}{
+		{0, []int{1000, 500}},
+		{5000, []int{1000, 500}},
+		{600, []int{600, 600, 300}},
+	}
+	for _, tt := range tests {
+		var mu sync.Mutex
+		var batches []int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			to := sendGridRecipients(t, r)
+			mu.Lock()
+			batches = append(batches, len(to))
+			mu.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+		}))
+
+		sender := SendGridSender{APIKey: "key", BatchSize: tt.batchSize, Client: serverClient(srv)}
+		if err := sender.Send(context.Background(), emailMessage{Subject: "Digest"}, recipients); err != nil {
+			t.Errorf("batch size %d: %v", tt.batchSize, err)
+		}
+		srv.Close()
+
+		if fmt.Sprint(batches) != fmt.Sprint(tt.want) {
+			t.Errorf("batch size %d: sent batches %v, want %v", tt.batchSize, batches, tt.want)
+		}
+	}
+}
+
+func TestSendGridBatchFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if to := sendGridRecipients(t, r); to[0] == "b@example.com" {
+			http.Error(w, `{"errors": [{"message": "quota exceeded"}]}`, http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sender := SendGridSender{APIKey: "key", BatchSize: 1, Client: serverClient(srv)}
+	err := sender.Send(context.Background(), emailMessage{Subject: "Digest"}, []string{"a@example.com", "b@example.com", "c@example.com"})
+
+	var recipientErr *RecipientError
+	if !errors.As(err, &recipientErr) {
+		t.Fatalf("Send() error = %v, want a *RecipientError", err)
+	}
+	if got := strings.Join(recipientErr.Accepted, ","); got != "a@example.com,c@example.com" {
+		t.Errorf("accepted = %s", got)
+	}
+	if len(recipientErr.Rejected) != 1 || recipientErr.Rejected["b@example.com"] == nil {
+		t.Errorf("rejected = %v, want only b@example.com", recipientErr.Rejected)
+	}
+}
+
+func TestSendGridEveryBatchFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors": [{"message": "Permission denied"}]}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	sender := SendGridSender{APIKey: "bad-key", BatchSize: 1, Client: serverClient(srv)}
+	err := sender.Send(context.Background(), emailMessage{Subject: "Digest"}, []string{"a@example.com", "b@example.com"})
+
+	if errors.As(err, new(*RecipientError)) {
+		t.Fatalf("Send() error = %v, want the batch error itself", err)
+	}
+	var emailErr *EmailError
+	if !errors.As(err, &emailErr) || !emailErr.AuthFailed() {
+		t.Errorf("Send() error = %v, want a 401 *EmailError", err)
+	}
+}
+
+func TestDeliverEmailRetriesRejectedRecipients(t *testing.T) {
+	defer func(d time.Duration) { emailRetryDelay = d }(emailRetryDelay)
+	emailRetryDelay = time.Millisecond
+
+	cfg := testConfig(t)
+	cfg.StateFile = filepath.Join(t.TempDir(), "state.json")
+	cfg.FromEmail, cfg.ToEmails = "bot@example.com", []string{"a@example.com", "b@example.com"}
+	sender := &fakeSender{err: &RecipientError{
+		Accepted: []string{"a@example.com"},
+		Rejected: map[string]error{"b@example.com": &EmailError{Provider: providerSendGrid, StatusCode: http.StatusTooManyRequests}},
+	}}
+	cfg.EmailSender = retrySender{sender}
+	articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z")}
+
+	if err := deliverEmail(context.Background(), cfg, articles, cfg.Location); err != nil {
+		t.Fatalf("deliverEmail() error = %v", err)
+	}
+	var sent []string
+	for _, e := range sender.sent {
+		sent = append(sent, strings.Join(e.to, ","))
+	}
+	if got := strings.Join(sent, " | "); got != "a@example.com,b@example.com | b@example.com" {
+		t.Errorf("sent to %s, want the retry to go to b@example.com only", got)
+	}
+}
+
+// retrySender fails like its fakeSender on the first send and succeeds after
+type retrySender struct{ *fakeSender }
+
+func (s retrySender) Send(ctx context.Context, msg emailMessage, to []string) error {
+	err := s.fakeSender.Send(ctx, msg, to)
+	s.fakeSender.err = nil
+	return err
+}
+
+func TestOverflowAttachment(t *testing.T) {
+	t.Setenv("MAX_EMAIL_ARTICLES", "2")
+	t.Setenv("ANONYMIZE_AUTHORS", "true")
+	cfg := testConfig(t)
+	cfg.StateFile = filepath.Join(t.TempDir(), "state.json")
+	cfg.FromEmail, cfg.ToEmails = "bot@example.com", []string{"a@example.com"}
+	cfg.EmailProvider, cfg.SendGridAPIKey = providerSendGrid, "key"
+
+	articles := []Article{
+		testArticle("u1", "2024-05-01T10:00:00Z"),
+		testArticle("u2", "2024-05-01T09:00:00Z"),
+		testArticle("u3", "2024-05-01T08:00:00Z"),
+	}
+	for i := range articles {
+		articles[i].Author.UserName = fmt.Sprintf("private_name_%d", i)
+	}
+
+	tests := []struct {
+		name     string
+		articles []Article
+		want     int
+	}{
+		{"fits", articles[:2], 0},
+		{"overflows", articles, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload SendGridEmail
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&payload)
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer srv.Close()
+			cfg.HTTPClient = serverClient(srv)
+			os.Remove(cfg.StateFile)
+
+			if err := deliverEmail(context.Background(), cfg, tt.articles, cfg.Location); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.want == 0 {
+				if len(payload.Attachments) != 0 {
+					t.Errorf("email has %d attachments, want none", len(payload.Attachments))
+				}
+				return
+			}
+			if len(payload.Attachments) != 1 || payload.Attachments[0].Filename != overflowAttachmentName {
+				t.Fatalf("attachments = %+v, want one %s", payload.Attachments, overflowAttachmentName)
+			}
+			data, err := base64.StdEncoding.DecodeString(payload.Attachments[0].Content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var attached []Article
+			if err := json.Unmarshal(data, &attached); err != nil {
+				t.Fatal(err)
+			}
+			if len(attached) != tt.want {
+				t.Errorf("attachment holds %d articles, want %d", len(attached), tt.want)
+			}
+			if strings.Contains(string(data), "private_name") {
+				t.Error("attachment contains a raw author name")
+			}
+		})
+	}
+}
+
+func TestSendGridTemplatePayload(t *testing.T) {
+	t.Setenv("SENDGRID_TEMPLATE_ID", "d-123")
+	cfg := testConfig(t)
+	cfg.StateFile = filepath.Join(t.TempDir(), "state.json")
+	cfg.FromEmail, cfg.ToEmails = "bot@example.com", []string{"a@example.com"}
+	cfg.EmailProvider, cfg.SendGridAPIKey = providerSendGrid, "key"
+
+	var payload map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	cfg.HTTPClient = serverClient(srv)
+
+	articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z"), testArticle("u2", "2024-05-01T09:00:00Z")}
+	if err := deliverEmail(context.Background(), cfg, articles, cfg.Location); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload["template_id"] != "d-123" {
+		t.Errorf("template_id = %v, want d-123", payload["template_id"])
+	}
+	if _, ok := payload["content"]; ok {
+		t.Error("template mode payload has a content array")
+	}
+	personalization := payload["personalizations"].([]interface{})[0].(map[string]interface{})
+	data, ok := personalization["dynamic_template_data"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("personalization has no dynamic_template_data: %v", personalization)
+	}
+	if data["count"] != float64(2) {
+		t.Errorf("count = %v, want 2", data["count"])
+	}
+	list := data["articles"].([]interface{})
+	if len(list) != 2 {
+		t.Fatalf("template data holds %d articles, want 2", len(list))
+	}
+	first := list[0].(map[string]interface{})
+	if first["title"] != "Article u1" || first["url"] != articleURL(articles[0]) {
+		t.Errorf("first article = %v", first)
+	}
+}
+
+func TestCardLayoutGolden(t *testing.T) {
+	t.Setenv("CARD_LAYOUT", "tags,reactions,summary")
+	cfg := testConfig(t)
+
+	article := testArticle("u1", "2024-05-01T10:00:00Z")
+	article.Summary = "Two pointers & a hash map."
+	article.Tags = []Tag{{Name: "Array", Slug: "array"}, {Name: "Hash Table", Slug: "hash-table"}}
+	article.Reactions = []Reaction{{ReactionType: "UPVOTE", Count: 12}}
+
+	var html strings.Builder
+	writeArticleCard(&html, article, 1, cfg, false)
+
+	want := readFile(t, testdataPath(t, "card_tags_first.golden"))
+	if got := html.String(); got != want {
+		t.Errorf("card does not match testdata/card_tags_first.golden\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	t.Setenv("CARD_LAYOUT", "tags,tags")
+	if _, err := loadConfig(); err == nil {
+		t.Error("CARD_LAYOUT with a repeated block accepted")
+	}
+	t.Setenv("CARD_LAYOUT", "tags,footer")
+	if _, err := loadConfig(); err == nil {
+		t.Error("CARD_LAYOUT with an unknown block accepted")
+	}
+}
+
+func TestFeaturedTags(t *testing.T) {
+	t.Setenv("FEATURED_TAGS", "Google, amazon")
+	cfg := testConfig(t)
+
+	article := testArticle("u1", "2024-05-01T10:00:00Z")
+	article.Tags = []Tag{
+		{Name: "Google", Slug: "google"},
+		{Name: "Arrays", Slug: "arrays"},
+		{Name: "Amazon", Slug: "amazon"},
+	}
+	html := generateHTMLEmail([]Article{article}, cfg.Location, cfg)
+
+	for _, want := range []string{
+		`<span class="tag tag-featured">Google</span>`,
+		`<span class="tag">Arrays</span>`,
+		`<span class="tag tag-featured">Amazon</span>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("email does not contain %s", want)
+		}
+	}
+	if n := strings.Count(html, `class="tag tag-featured"`); n != 2 {
+		t.Errorf("%d featured chips, want 2", n)
+	}
+}
+
+func TestTruncateTextUTF8(t *testing.T) {
+	tests := []struct {
+		in     string
+		maxLen int
+		want   string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"héllo wörld", 5, "héllo..."},
+		{"二分查找算法详解", 4, "二分查找..."},
+		{"🚀🚀🚀🚀", 2, "🚀🚀..."},
+	}
+	for _, tt := range tests {
+		got := truncateText(tt.in, tt.maxLen)
+		if got != tt.want {
+			t.Errorf("truncateText(%q, %d) = %q, want %q", tt.in, tt.maxLen, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncateText(%q, %d) is not valid UTF-8", tt.in, tt.maxLen)
+		}
+	}
+}
+
+func TestTrackingPixel(t *testing.T) {
+	articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z")}
+
+	cfg := testConfig(t)
+	if html := generateHTMLEmail(articles, cfg.Location, cfg); strings.Contains(html, `width="1" height="1"`) {
+		t.Error("pixel rendered without TRACKING_PIXEL_URL")
+	}
+
+	t.Setenv("TRACKING_PIXEL_URL", "https://t.example.com/p.gif?src=mail")
+	cfg = testConfig(t)
+	html := generateHTMLEmail(articles, cfg.Location, cfg)
+	want := `<img src="https://t.example.com/p.gif?run=` + digestID(articles) + `&amp;src=mail" width="1" height="1"`
+	if !strings.Contains(html, want) {
+		t.Errorf("email does not contain the pixel %s", want)
+	}
+
+	if got := trackingPixelURL("https://t.example.com/p.gif", "run 1/a&b"); got != "https://t.example.com/p.gif?run=run+1%2Fa%26b" {
+		t.Errorf("run ID not URL-escaped: %s", got)
+	}
+
+	t.Setenv("TRACKING_PIXEL_URL", "javascript:alert(1)")
+	if _, err := loadConfig(); err == nil {
+		t.Error("non-http TRACKING_PIXEL_URL accepted")
+	}
+}
+
+func TestPlainTextAlternative(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.FromEmail, cfg.EmailProvider, cfg.SendGridAPIKey = "bot@example.com", providerSendGrid, "key"
+	cfg.EmailFormat.SummaryLen = 20
+	first := testArticle("u1", "2024-05-01T10:00:00Z")
+	first.Summary = "A summary that is longer than twenty characters."
+	second := testArticle("u2", "2024-05-01T09:00:00Z")
+
+	var payload SendGridEmail
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("SendGrid payload is not JSON: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	cfg.HTTPClient = serverClient(srv)
+
+	if err := sendDigestEmail(context.Background(), cfg, []Article{first, second}, []string{"a@example.com"}, cfg.Location); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Content) != 2 || payload.Content[0].Type != "text/plain" || payload.Content[1].Type != "text/html" {
+		t.Fatalf("content parts %+v, want text/plain then text/html", payload.Content)
+	}
+
+	text := payload.Content[0].Value
+	for _, want := range []string{
+		"\n1. Article u1\n   " + articleURL(first) + "\n   By author-u1 • 2024-05-01 15:30:00 IST\n   A summary that is lo...\n",
+		"\n2. Article u2\n   " + articleURL(second) + "\n   By author-u2 • 2024-05-01 14:30:00 IST\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("plain text lacks %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "<") {
+		t.Errorf("plain text contains markup:\n%s", text)
+	}
+}
+
+func TestSenderForProvider(t *testing.T) {
+	tests := []struct {
+		args []string
+		env  map[string]string
+		want EmailSender
+	}{
+		{nil, nil, SendGridSender{APIKey: "sg-key", BatchSize: sendGridMaxRecipients}},
+		{[]string{"-provider", "sendgrid"}, nil, SendGridSender{APIKey: "sg-key", BatchSize: sendGridMaxRecipients}},
+		{
+			[]string{"-provider", "smtp"},
+			map[string]string{"SMTP_HOST": "smtp.example.com", "SMTP_PORT": "2525", "SMTP_USER": "me", "SMTP_PASS": "secret"},
+			SMTPSender{Config: SMTPConfig{Host: "smtp.example.com", Port: "2525", Username: "me", Password: "secret"}},
+		},
+		{[]string{"-provider", "SMTP"}, map[string]string{"SMTP_HOST": "smtp.example.com"}, SMTPSender{Config: SMTPConfig{Host: "smtp.example.com", Port: "587"}}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			t.Setenv("SENDGRID_API_KEY", "sg-key")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg, err := loadCommandConfig("test", tt.args, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.Sender(); fmt.Sprintf("%#v", got) != fmt.Sprintf("%#v", tt.want) {
+				t.Errorf("Sender() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := loadCommandConfig("test", []string{"-provider", "pigeon"}, nil); err == nil {
+		t.Error("-provider pigeon was accepted")
+	}
+}
+
+func TestDeliverEmailWithFakeSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{"accepted", nil, false},
+		{"failed", errors.New("connection refused"), false},
+		{"every recipient rejected", &RecipientError{Rejected: map[string]error{"a@example.com": errors.New("550")}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.StateFile = filepath.Join(t.TempDir(), "state.json")
+			cfg.FromEmail, cfg.ToEmails = "bot@example.com", []string{"a@example.com", "b@example.com"}
+			sender := &fakeSender{err: tt.err}
+			cfg.EmailSender = sender
+			articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z"), testArticle("u2", "2024-05-01T09:00:00Z")}
+
+			if err := deliverEmail(context.Background(), cfg, articles, cfg.Location); (err != nil) != tt.wantErr {
+				t.Fatalf("deliverEmail() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(sender.sent) != 1 {
+				t.Fatalf("sent %d emails, want 1", len(sender.sent))
+			}
+			got := sender.sent[0]
+			if to := strings.Join(got.to, ","); to != "a@example.com,b@example.com" {
+				t.Errorf("sent to %s", to)
+			}
+			if got.msg.FromEmail != "bot@example.com" || got.msg.Subject != "📚 LeetCode Daily Digest - 2 New Articles" {
+				t.Errorf("from %q with subject %q", got.msg.FromEmail, got.msg.Subject)
+			}
+			for _, uuid := range []string{"u1", "u2"} {
+				if !strings.Contains(got.msg.HTML, "Article "+uuid) || !strings.Contains(got.msg.Text, "Article "+uuid) {
+					t.Errorf("email bodies lack article %s", uuid)
+				}
+			}
+		})
+	}
+}
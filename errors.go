@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FetchError describes a failed request to the LeetCode API
+type FetchError struct {
+	StatusCode int    // HTTP status, 0 when no response was received
+	Skip       int    // pagination offset of the request
+	First      int    // page size of the request
+	Body       string // start of the response body, if any
+	Err        error  // underlying cause
+
+	RetryAfter time.Duration // wait requested by a Retry-After header, if any
+}
+
+func (e *FetchError) Error() string {
+	msg := fmt.Sprintf("fetching articles (skip=%d, first=%d) failed", e.Skip, e.First)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Body != "" {
+		msg += ". Response: " + e.Body
+	}
+	return msg
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// graphQLErrorMessages joins the messages of a GraphQL errors array
+func graphQLErrorMessages(errs []GraphQLError) string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// RateLimited reports whether LeetCode asked us to slow down
+func (e *FetchError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// AuthFailed reports whether LeetCode rejected the request's credentials or client
+func (e *FetchError) AuthFailed() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// ServerError reports whether LeetCode failed on its side
+func (e *FetchError) ServerError() bool {
+	return e.StatusCode >= 500
+}
+
+// Transient reports whether repeating the request may succeed: network failures,
+// rate limiting and temporary server errors
+func (e *FetchError) Transient() bool {
+	switch e.StatusCode {
+	case 0:
+		return true
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// EmailError describes a failed request to an email provider
+type EmailError struct {
+	Provider   string   // providerSendGrid, providerMailgun, ...
+	StatusCode int      // HTTP status, 0 when no response was received
+	Messages   []string // error messages reported by the provider
+	Err        error    // underlying cause, if any
+}
+
+func (e *EmailError) Error() string {
+	msg := e.Provider + " request failed"
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("%s API returned status %d", e.Provider, e.StatusCode)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if len(e.Messages) > 0 {
+		msg += ": " + strings.Join(e.Messages, "; ")
+	}
+	return msg
+}
+
+func (e *EmailError) Unwrap() error {
+	return e.Err
+}
+
+// Transient reports whether retrying the send later may succeed
+func (e *EmailError) Transient() bool {
+	return e.StatusCode == 0 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// AuthFailed reports whether the provider rejected our credentials
+func (e *EmailError) AuthFailed() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// TooLarge reports whether the provider rejected the message because of its size
+func (e *EmailError) TooLarge() bool {
+	return e.StatusCode == http.StatusRequestEntityTooLarge
+}
+
+// messageTooLarge reports whether err is a size rejection from any provider:
+// an HTTP 413 or the SMTP reply 552 (message exceeds the maximum size)
+func messageTooLarge(err error) bool {
+	var emailErr *EmailError
+	if errors.As(err, &emailErr) && emailErr.TooLarge() {
+		return true
+	}
+	var smtpErr *textproto.Error
+	return errors.As(err, &smtpErr) && smtpErr.Code == 552
+}
+
+// SlackError describes a failed post to a Slack incoming webhook
+type SlackError struct {
+	StatusCode int    // HTTP status, 0 when no response was received
+	Reason     string // error code Slack sent in the body, such as "invalid_payload"
+	Err        error  // underlying cause, if any
+}
+
+func (e *SlackError) Error() string {
+	msg := "slack request failed"
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("slack webhook returned status %d", e.StatusCode)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *SlackError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidPayload reports whether Slack could not accept the message blocks
+func (e *SlackError) InvalidPayload() bool {
+	return e.Reason == "invalid_payload"
+}
+
+// DiscordError describes a failed post to a Discord webhook
+type DiscordError struct {
+	StatusCode int    // HTTP status, 0 when no response was received
+	Message    string // error message Discord sent, if any
+	Err        error  // underlying cause, if any
+
+	RetryAfter time.Duration // wait requested by X-RateLimit-Reset-After, if any
+}
+
+func (e *DiscordError) Error() string {
+	msg := "discord request failed"
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("discord webhook returned status %d", e.StatusCode)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func (e *DiscordError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimited reports whether Discord asked us to slow down
+func (e *DiscordError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// RecipientError reports recipients the email provider rejected, such as SMTP
+// RCPT failures or a failed SendGrid batch. The message was still delivered to
+// the accepted recipients, if any.
+type RecipientError struct {
+	Accepted []string
+	Rejected map[string]error
+}
+
+func (e *RecipientError) Error() string {
+	emails := make([]string, 0, len(e.Rejected))
+	for email := range e.Rejected {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+
+	const maxListed = 10
+	var parts []string
+	for i, email := range emails {
+		if i == maxListed {
+			parts = append(parts, fmt.Sprintf("and %d more", len(emails)-maxListed))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s (%v)", email, e.Rejected[email]))
+	}
+	return fmt.Sprintf("%d of %d recipients were rejected: %s",
+		len(e.Rejected), len(e.Rejected)+len(e.Accepted), strings.Join(parts, "; "))
+}
+
+// Unwrap returns the distinct rejection errors, so errors.As can find an
+// *EmailError behind a partly failed send
+func (e *RecipientError) Unwrap() []error {
+	emails := make([]string, 0, len(e.Rejected))
+	for email := range e.Rejected {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+
+	var errs []error
+	seen := make(map[error]bool)
+	for _, email := range emails {
+		if err := e.Rejected[email]; !seen[err] {
+			seen[err] = true
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// AllRejected reports whether no recipient accepted the message
+func (e *RecipientError) AllRejected() bool {
+	return len(e.Accepted) == 0
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchErrorFields(t *testing.T) {
+	tests := []struct {
+		status      int
+		rateLimited bool
+		authFailed  bool
+		serverError bool
+	}{
+		{http.StatusTooManyRequests, true, false, false},
+		{http.StatusUnauthorized, false, true, false},
+		{http.StatusServiceUnavailable, false, false, true},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "7")
+			http.Error(w, "slow down", tt.status)
+		}))
+
+		_, _, err := fetchDiscussPage(context.Background(), 25, 50, clientOptions{HTTPClient: serverClient(srv)})
+		srv.Close()
+
+		var fetchErr *FetchError
+		if !errors.As(err, &fetchErr) {
+			t.Fatalf("status %d: error %v is not a *FetchError", tt.status, err)
+		}
+		if fetchErr.StatusCode != tt.status || fetchErr.Skip != 50 || fetchErr.First != 25 {
+			t.Errorf("status %d: got status=%d skip=%d first=%d", tt.status, fetchErr.StatusCode, fetchErr.Skip, fetchErr.First)
+		}
+		if !strings.Contains(fetchErr.Body, "slow down") {
+			t.Errorf("status %d: body = %q", tt.status, fetchErr.Body)
+		}
+		if fetchErr.RateLimited() != tt.rateLimited || fetchErr.AuthFailed() != tt.authFailed || fetchErr.ServerError() != tt.serverError {
+			t.Errorf("status %d: classified as rateLimited=%v authFailed=%v serverError=%v",
+				tt.status, fetchErr.RateLimited(), fetchErr.AuthFailed(), fetchErr.ServerError())
+		}
+		if tt.rateLimited && fetchErr.RetryAfter != 7*time.Second {
+			t.Errorf("RetryAfter = %v, want 7s", fetchErr.RetryAfter)
+		}
+	}
+}
+
+func TestEmailErrorFromSendGrid(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"errors": [{"message": "The provided authorization grant is invalid", "field": "authorization"}, {"message": "Permission denied"}]}`))
+	}))
+	defer srv.Close()
+
+	sender := SendGridSender{APIKey: "bad-key", Client: serverClient(srv)}
+	err := sender.Send(context.Background(), emailMessage{Subject: "Digest"}, []string{"a@example.com"})
+
+	var emailErr *EmailError
+	if !errors.As(err, &emailErr) {
+		t.Fatalf("Send() error = %v, want an *EmailError", err)
+	}
+	if emailErr.Provider != providerSendGrid || emailErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("provider=%q status=%d", emailErr.Provider, emailErr.StatusCode)
+	}
+	want := []string{"The provided authorization grant is invalid (field authorization)", "Permission denied"}
+	if strings.Join(emailErr.Messages, "|") != strings.Join(want, "|") {
+		t.Errorf("messages = %q, want %q", emailErr.Messages, want)
+	}
+	if !emailErr.AuthFailed() || emailErr.Transient() {
+		t.Errorf("401 classified as authFailed=%v transient=%v", emailErr.AuthFailed(), emailErr.Transient())
+	}
+}
+
+func TestRecipientErrorUnwrap(t *testing.T) {
+	quota := &EmailError{Provider: providerSendGrid, StatusCode: http.StatusTooManyRequests}
+	err := &RecipientError{
+		Accepted: []string{"a@example.com"},
+		Rejected: map[string]error{"b@example.com": quota, "c@example.com": quota},
+	}
+
+	if got := err.Unwrap(); len(got) != 1 {
+		t.Errorf("Unwrap() = %v, want the shared error once", got)
+	}
+	var emailErr *EmailError
+	if !errors.As(err, &emailErr) || !emailErr.Transient() {
+		t.Errorf("errors.As did not find the transient *EmailError in %v", err)
+	}
+}
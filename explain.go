@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// explainRun describes what a digest run with cfg would do: the cutoff, the
+// filters, the outputs and the delivery channels. Only the state file is read;
+// nothing is fetched, written or sent.
+func explainRun(w io.Writer, cfg Config, enableEmail bool, now time.Time) error {
+	state, err := readState(cfg.StateFile)
+	if err != nil {
+		return err
+	}
+	loc := cfg.Location
+	const layout = "2006-01-02 03:04 PM MST"
+
+	fmt.Fprintln(w, "Run plan (nothing is fetched, written or sent):")
+
+	fmt.Fprintln(w, "\nCutoff")
+	switch {
+	case !cfg.Since.IsZero():
+		fmt.Fprintf(w, "  Articles published after %s, set by -since\n", cfg.Since.In(loc).Format(layout))
+		fmt.Fprintf(w, "  The cutoff and seen articles in %s are ignored; the saved cutoff is only ever advanced\n", cfg.StateFile)
+	case state.LastProcessed.IsZero():
+		fmt.Fprintf(w, "  Articles published after %s\n", resolveCutoff(state.LastProcessed, cfg.MaxAge, now).In(loc).Format(layout))
+		fmt.Fprintf(w, "  No previous run in %s, so the last %s are fetched\n", cfg.StateFile, firstRunWindow)
+	default:
+		fmt.Fprintf(w, "  Articles published after %s\n", resolveCutoff(state.LastProcessed, cfg.MaxAge, now).In(loc).Format(layout))
+		fmt.Fprintf(w, "  Last processed %s, from %s\n", state.LastProcessed.In(loc).Format(layout), cfg.StateFile)
+	}
+	if cfg.MaxAge > 0 && cfg.Since.IsZero() {
+		fmt.Fprintf(w, "  MAX_AGE limits the fetch to the last %s\n", cfg.MaxAge)
+	}
+	if cfg.MinRunInterval > 0 && !state.LastRun.IsZero() {
+		since := now.Sub(state.LastRun).Round(time.Second)
+		if since < cfg.MinRunInterval {
+			fmt.Fprintf(w, "  The last run started %s ago, within MIN_RUN_INTERVAL (%s): this run would be skipped without --force\n", since, cfg.MinRunInterval)
+		} else {
+			fmt.Fprintf(w, "  The last run started %s ago, outside MIN_RUN_INTERVAL (%s)\n", since, cfg.MinRunInterval)
+		}
+	}
+	if cfg.MaxRuntime > 0 {
+		fmt.Fprintf(w, "  Fetching stops early enough to deliver within MAX_RUNTIME (%s)\n", cfg.MaxRuntime)
+	}
+
+	fmt.Fprintln(w, "\nSource")
+	if cfg.Source == "" || cfg.Source == "leetcode" {
+		fmt.Fprintf(w, "  LeetCode API, ordered by %s\n", cfg.ClientOptions().orderBy())
+		if cfg.MaxPages > 0 {
+			fmt.Fprintf(w, "  At most %d pages of %d articles\n", cfg.MaxPages, batchSize)
+		}
+		if cfg.FetchWorkers > 1 {
+			fmt.Fprintf(w, "  Up to %d pages requested at once after the first\n", cfg.FetchWorkers)
+		}
+	} else {
+		fmt.Fprintf(w, "  %s\n", cfg.Source)
+	}
+	if cfg.FullContent {
+		fmt.Fprintln(w, "  Full bodies are fetched for reported articles, one request each")
+	}
+
+	fmt.Fprintln(w, "\nFilters")
+	var filters []string
+	if len(cfg.TagSlugs) > 0 {
+		filters = append(filters, "Tags: "+strings.Join(cfg.TagSlugs, ", "))
+	}
+	if len(cfg.Keywords) > 0 {
+		filters = append(filters, "Keywords: "+strings.Join(cfg.Keywords, ", "))
+	}
+	if len(cfg.RequireReactionTypes) > 0 {
+		filters = append(filters, "Reaction types: "+strings.Join(cfg.RequireReactionTypes, ", "))
+	}
+	if cfg.MinReactions > 0 {
+		filters = append(filters, fmt.Sprintf("At least %d reactions", cfg.MinReactions))
+	}
+	if cfg.MaxPerAuthor > 0 {
+		filters = append(filters, fmt.Sprintf("At most %d articles per author", cfg.MaxPerAuthor))
+	}
+	if len(state.SeenUUIDs) > 0 {
+		filters = append(filters, fmt.Sprintf("Skips %d articles already reported, matched by %s", len(state.SeenUUIDs), cfg.DedupKey))
+	}
+	if cfg.ArchiveIndex != "" {
+		filters = append(filters, "Skips articles archived in "+cfg.ArchiveIndex)
+	}
+	if cfg.SortBy != "" {
+		filters = append(filters, "Sorted by "+cfg.SortBy)
+	}
+	if cfg.MaxArticles > 0 {
+		filters = append(filters, fmt.Sprintf("At most the %d most recent articles are reported", cfg.MaxArticles))
+	}
+	if cfg.AlwaysShowLatest > 0 {
+		filters = append(filters, fmt.Sprintf("Filled up to the latest %d articles, new or not", cfg.AlwaysShowLatest))
+	}
+	if len(filters) == 0 {
+		filters = append(filters, "None")
+	}
+	for _, filter := range filters {
+		fmt.Fprintf(w, "  %s\n", filter)
+	}
+
+	fmt.Fprintln(w, "\nOutput")
+	if cfg.EnableFileOutput {
+		name, err := renderFilename(cfg.FilenameTemplate, now.In(loc), 0, formatExtension(cfg.OutputFormat), cfg.RunLabel)
+		if err != nil {
+			return err
+		}
+		mode := "a new file per run"
+		if cfg.DailyFile {
+			mode = "appended to the day's file"
+		}
+		fmt.Fprintf(w, "  %s file, %s, such as %s\n", cfg.OutputFormat, mode, filepath.Join("fetched_articles", name))
+	} else {
+		fmt.Fprintln(w, "  No file output (ENABLE_FILE_OUTPUT=false)")
+	}
+	if cfg.ChunkWindow > 0 {
+		fmt.Fprintf(w, "  Delivered in windows of %s\n", cfg.ChunkWindow)
+	}
+	if cfg.CompressOlderThan > 0 {
+		fmt.Fprintf(w, "  Text files in fetched_articles older than %s are gzipped afterwards\n", cfg.CompressOlderThan)
+	}
+	if cfg.StatsJSONL != "" {
+		fmt.Fprintf(w, "  Run stats appended to %s\n", cfg.StatsJSONL)
+	}
+
+	fmt.Fprintln(w, "\nDelivery")
+	if enableEmail {
+		fmt.Fprintf(w, "  Email via %s to %s\n", cfg.EmailProvider, strings.Join(cfg.ToEmails, ", "))
+	} else {
+		fmt.Fprintln(w, "  No email")
+	}
+	for _, notifier := range cfg.Notifiers(nil) {
+		fmt.Fprintf(w, "  %s\n", notifier.Name())
+	}
+	if cfg.WeeklyReport {
+		fmt.Fprintf(w, "  Weekly stats email on %s, from the articles collected in %s\n", cfg.WeeklyReportDay, cfg.WeeklyReportFile)
+	}
+	if cfg.PostRunHook != "" {
+		fmt.Fprintf(w, "  Post-run hook: %s\n", cfg.PostRunHook)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplainRun(t *testing.T) {
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		state       *State
+		env         map[string]string
+		enableEmail bool
+		want        []string
+		wantNot     []string
+	}{
+		{
+			name: "first run, file only",
+			want: []string{
+				"  Articles published after 2024-05-01 03:30 PM IST\n",
+				"  No previous run in state.json, so the last 24h0m0s are fetched\n",
+				"\nDelivery\n  No email\n",
+			},
+			wantNot: []string{"Slack", "Discord"},
+		},
+		{
+			name:  "saved cutoff and notifiers",
+			state: &State{LastProcessed: time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)},
+			env: map[string]string{
+				"SLACK_WEBHOOK_URL":   "https://hooks.slack.com/services/T/B/X",
+				"DISCORD_WEBHOOK_URL": "https://discord.com/api/webhooks/1/x",
+				"FROM_EMAIL":          "bot@example.com", "RECIPIENTS": "a@example.com", "SENDGRID_API_KEY": "key",
+			},
+			enableEmail: true,
+			want: []string{
+				"  Articles published after 2024-05-02 01:30 AM IST\n",
+				"  Last processed 2024-05-02 01:30 AM IST, from state.json\n",
+				"  Email via sendgrid to a@example.com\n  Slack\n  Discord\n",
+			},
+		},
+		{
+			name:  "MAX_AGE overrides an old cutoff",
+			state: &State{LastProcessed: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+			env:   map[string]string{"MAX_AGE": "48h"},
+			want:  []string{"  Articles published after 2024-04-30 03:30 PM IST\n", "  MAX_AGE limits the fetch to the last 48h0m0s\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg := testConfig(t)
+			cfg.StateFile = "state.json"
+			// as -slack and -discord would
+			cfg.PostToSlack, cfg.PostToDiscord = cfg.SlackWebhookURL != "", cfg.DiscordWebhookURL != ""
+			if tt.state != nil {
+				if err := writeState(cfg.StateFile, *tt.state); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var out strings.Builder
+			if err := explainRun(&out, cfg, tt.enableEmail, now); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("explanation lacks %q:\n%s", want, out.String())
+				}
+			}
+			for _, unwanted := range tt.wantNot {
+				if strings.Contains(out.String(), unwanted) {
+					t.Errorf("explanation mentions %q:\n%s", unwanted, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestExplainMakesNoRequests(t *testing.T) {
+	inTempDir(t)
+	t.Setenv("STATE_FILE", "state.json")
+	t.Setenv("SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T/B/X")
+	routeDefaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("--explain made a request to %s", r.URL.Path)
+	})
+
+	var code int
+	out := captureStdout(t, func() { code = cmdDigest(context.Background(), []string{"-explain", "-slack"}) })
+	if code != 0 {
+		t.Fatalf("cmdDigest = %d, want 0", code)
+	}
+	if !strings.Contains(out, "Run plan") || !strings.Contains(out, "  Slack\n") {
+		t.Errorf("unexpected explanation:\n%s", out)
+	}
+	entries, _ := os.ReadDir(".")
+	if len(entries) != 0 {
+		t.Errorf("--explain left files behind: %v", entries)
+	}
+}
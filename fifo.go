@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// fifoOpenTimeout is how long writing to a named pipe waits for a reader
+const fifoOpenTimeout = 30 * time.Second
+
+// fifoPollInterval is how often openFIFO checks for a reader
+const fifoPollInterval = 100 * time.Millisecond
+
+// isNamedPipe reports whether path exists and is a named pipe (FIFO)
+func isNamedPipe(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeNamedPipe != 0
+}
+
+// openFIFO opens a named pipe for writing once a reader has it open, giving up
+// after timeout. A plain blocking open could not be abandoned, so the open is
+// retried without blocking: it fails with ENXIO for as long as there is no reader.
+func openFIFO(path string, timeout time.Duration) (*os.File, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if err == nil {
+			return file, nil
+		}
+		if !errors.Is(err, syscall.ENXIO) {
+			return nil, fmt.Errorf("failed to open pipe: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no reader opened pipe %s within %s", path, timeout)
+		}
+		time.Sleep(fifoPollInterval)
+	}
+}
@@ -0,0 +1,63 @@
+//go:build unix
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWriteToFIFO(t *testing.T) {
+	cfg := testConfig(t)
+	for _, atomic := range []bool{false, true} {
+		cfg.AtomicWrite = atomic
+		path := filepath.Join(t.TempDir(), "digest.fifo")
+		if err := syscall.Mkfifo(path, 0644); err != nil {
+			t.Skipf("named pipes not supported: %v", err)
+		}
+
+		read := make(chan string)
+		go func() {
+			f, err := os.Open(path)
+			if err != nil {
+				read <- err.Error()
+				return
+			}
+			defer f.Close()
+			data, _ := io.ReadAll(f)
+			read <- string(data)
+		}()
+
+		articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z")}
+		if err := writeArticlesToFile(articles, path, cfg); err != nil {
+			t.Fatalf("atomic=%v: %v", atomic, err)
+		}
+		if got := <-read; !strings.Contains(got, "UUID: u1") {
+			t.Errorf("atomic=%v: reader got %q, want the digest", atomic, got)
+		}
+		if !isNamedPipe(path) {
+			t.Errorf("atomic=%v: %s was replaced by a regular file", atomic, path)
+		}
+	}
+}
+
+func TestFIFOWithoutReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "digest.fifo")
+	if err := syscall.Mkfifo(path, 0644); err != nil {
+		t.Skipf("named pipes not supported: %v", err)
+	}
+
+	start := time.Now()
+	_, err := openFIFO(path, 200*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "no reader opened pipe") {
+		t.Fatalf("openFIFO() error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("gave up after %s, want about 200ms", elapsed)
+	}
+}
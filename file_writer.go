@@ -1,65 +1,422 @@
 package main
 
 import (
+	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// defaultFilenameTemplate reproduces the original leetcode_articles_<timestamp>.txt naming
+const defaultFilenameTemplate = "leetcode_articles_{2006-01-02_15-04-05}.{ext}"
+
+// dailyFilenameTemplate names the file by date only so a day's runs share it
+const dailyFilenameTemplate = "leetcode_articles_{2006-01-02}.{ext}"
+
+// Output file formats selected with OUTPUT_FORMAT or -format
+const (
+	formatText     = "txt"
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+	formatRSS      = "rss"
+)
+
+// parseOutputFormat validates an output format name; "md" is accepted for Markdown
+func parseOutputFormat(v string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(v)); f {
+	case formatText, formatJSON, formatMarkdown, formatRSS:
+		return f, nil
+	case "md":
+		return formatMarkdown, nil
+	default:
+		return "", fmt.Errorf("unknown format %q: must be %s, %s, %s or %s", v, formatText, formatJSON, formatMarkdown, formatRSS)
+	}
+}
+
+// formatExtension returns the file extension used for an output format
+func formatExtension(format string) string {
+	switch format {
+	case formatMarkdown:
+		return "md"
+	case formatRSS:
+		return "xml"
+	}
+	return format
+}
+
+// labelledTemplate makes sure a filename template includes the run label, adding
+// "_{label}" before the extension when it does not mention it
+func labelledTemplate(template string) string {
+	if strings.Contains(template, "{label}") {
+		return template
+	}
+	return suffixedTemplate(template, "_{label}")
+}
+
+// suffixedTemplate adds suffix to a filename template before the extension
+func suffixedTemplate(template, suffix string) string {
+	if strings.Contains(template, ".{ext}") {
+		return strings.Replace(template, ".{ext}", suffix+".{ext}", 1)
+	}
+	return template + suffix
+}
+
+// fileTitle is the heading of output files, naming the run label when there is one
+func fileTitle(label string) string {
+	if label == "" {
+		return "LeetCode Discuss"
+	}
+	return "LeetCode Discuss - " + label + " digest"
+}
+
+// groupByDay is the FILE_GROUP_BY value that puts articles under date headings
+const groupByDay = "day"
+
+var templatePlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// renderFilename expands a filename template. {ext}, {count} and {label} are replaced
+// with the file extension, article count and run label; any other {...} is treated
+// as a Go time layout.
+func renderFilename(template string, now time.Time, count int, ext, label string) (string, error) {
+	name := templatePlaceholder.ReplaceAllStringFunc(template, func(m string) string {
+		switch token := m[1 : len(m)-1]; token {
+		case "ext":
+			return ext
+		case "count":
+			return strconv.Itoa(count)
+		case "label":
+			return label
+		default:
+			return now.Format(token)
+		}
+	})
+
+	if err := validateFilename(name); err != nil {
+		return "", fmt.Errorf("filename template %q: %w", template, err)
+	}
+	return name, nil
+}
+
+// validateFilename rejects names that are unsafe to create inside the output directory
+func validateFilename(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("produces an empty or reserved name %q", name)
+	}
+	if i := strings.IndexAny(name, `/\:*?"<>|`); i >= 0 {
+		return fmt.Errorf("produces %q containing the unsafe character %q", name, name[i])
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("produces %q containing a control character", name)
+		}
+	}
+	return nil
+}
+
 // writeArticlesToFile formats and writes all article data to a file
-func writeArticlesToFile(articles []Article, filename string) error {
-	file, err := os.Create(filename)
+func writeArticlesToFile(articles []Article, filename string, cfg Config) error {
+	return writeOutput(filename, cfg.AtomicWrite, func(w io.Writer) {
+		// Write header
+		fmt.Fprintf(w, "%s - Latest %d Articles%s\n", fileTitle(cfg.RunLabel), len(articles), showingNote(len(articles), cfg))
+		fmt.Fprintf(w, "Fetched on: %s\n", time.Now().In(cfg.Location).Format(cfg.DateFormat.DateTime))
+		fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", cfg.SeparatorWidth))
+
+		writeArticleSections(w, articles, 1, cfg)
+		if cfg.FileIncludeURLList {
+			writeURLList(w, articles)
+		}
+	})
+}
+
+// appendArticlesToFile adds articles to an existing digest file under a run separator,
+// skipping any whose UUID the file already contains. A missing file is written in full.
+// It returns the number of articles added.
+func appendArticlesToFile(articles []Article, filename string, cfg Config) (added int, err error) {
+	seen, err := readArticleUUIDs(filename)
+	if err != nil {
+		return 0, err
+	}
+	if seen == nil {
+		return len(articles), writeArticlesToFile(articles, filename, cfg)
+	}
+
+	fresh := excludeSeenArticles(articles, seen)
+	if len(fresh) == 0 {
+		return 0, nil
+	}
+
+	writeRun := func(w io.Writer) {
+		// Write run separator
+		fmt.Fprintf(w, "%s\n", strings.Repeat("=", cfg.SeparatorWidth))
+		fmt.Fprintf(w, "Run on: %s - %d New Articles\n", time.Now().In(cfg.Location).Format(cfg.DateFormat.DateTime), len(fresh))
+		fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", cfg.SeparatorWidth))
+
+		writeArticleSections(w, fresh, len(seen)+1, cfg)
+		if cfg.FileIncludeURLList {
+			writeURLList(w, fresh)
+		}
+	}
+
+	if cfg.AtomicWrite {
+		// Rewrite the whole file so a crash never leaves a half-appended run
+		existing, err := os.ReadFile(filename)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read existing file: %w", err)
+		}
+		err = writeOutput(filename, true, func(w io.Writer) {
+			w.Write(existing)
+			writeRun(w)
+		})
+		return len(fresh), err
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer closeWithError(file, &err)
+
+	ew := &errWriter{w: file}
+	writeRun(ew)
+	return len(fresh), ew.err
+}
+
+// readArticleUUIDs collects the UUIDs of articles already written to a digest file,
+// which may be gzipped (.gz). Only the UUID line that opens each article section
+// counts, so summaries that happen to contain "UUID: " are not mistaken for
+// articles. It returns nil when the file does not exist.
+func readArticleUUIDs(filename string) (map[string]bool, error) {
+	data, err := readMaybeGzipped(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read existing file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	inHeader := false
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "Article #"):
+			inHeader = true
+		case inHeader && strings.HasPrefix(line, "UUID: "):
+			seen[strings.TrimSpace(strings.TrimPrefix(line, "UUID: "))] = true
+			inHeader = false
+		case inHeader && strings.HasPrefix(line, "Title: "):
+			inHeader = false // Section without a UUID line
+		}
+	}
+	return seen, nil
+}
+
+// readMaybeGzipped reads a file, decompressing it when its name ends in .gz
+func readMaybeGzipped(filename string) ([]byte, error) {
+	if !strings.HasSuffix(filename, ".gz") {
+		return os.ReadFile(filename)
+	}
+
+	file, err := os.Open(filename)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return nil, err
 	}
 	defer file.Close()
 
-	// Write header
-	ist := time.FixedZone("IST", 5*3600+30*60)
-	fmt.Fprintf(file, "LeetCode Discuss - Latest %d Articles\n", len(articles))
-	fmt.Fprintf(file, "Fetched on: %s\n", time.Now().In(ist).Format("2006-01-02 15:04:05 MST"))
-	fmt.Fprintf(file, "%s\n\n", strings.Repeat("=", 80))
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
 
+// excludeSeenArticles drops articles whose UUID is in seen, as well as repeats
+// within articles itself
+func excludeSeenArticles(articles []Article, seen map[string]bool) []Article {
+	return excludeSeenKeys(articles, seen, dedupUUID)
+}
+
+// writeArticleSections writes one section per article, numbering from start; a
+// SORT_BY rank uses the same number. With FILE_GROUP_BY=day a date heading (in the
+// configured time zone) opens each day's articles.
+func writeArticleSections(w io.Writer, articles []Article, start int, cfg Config) {
+	lastDay := ""
 	for i, article := range articles {
-		fmt.Fprintf(file, "%s\n", strings.Repeat("═", 80))
-		fmt.Fprintf(file, "Article #%d\n", i+1)
-		fmt.Fprintf(file, "%s\n\n", strings.Repeat("═", 80))
+		if cfg.FileGroupBy == groupByDay {
+			if day := formatStringTimestamp(article.CreatedAt, "2006-01-02", cfg.Location); day != lastDay {
+				fmt.Fprintf(w, "=== %s ===\n\n", day)
+				lastDay = day
+			}
+		}
+
+		n := start + i
+		fmt.Fprintf(w, "%s\n", strings.Repeat("═", cfg.SeparatorWidth))
+		if rank := rankLabel(n, article, cfg); rank != "" {
+			fmt.Fprintf(w, "Article #%d - %s\n", n, rank)
+		} else {
+			fmt.Fprintf(w, "Article #%d\n", n)
+		}
+		fmt.Fprintf(w, "%s\n\n", strings.Repeat("═", cfg.SeparatorWidth))
 
 		// Basic article info
-		fmt.Fprintf(file, "UUID: %s\n", article.UUID)
-		fmt.Fprintf(file, "Title: %s\n", article.Title)
-		fmt.Fprintf(file, "Slug: %s\n", article.Slug)
-		fmt.Fprintf(file, "Article Type: %s\n", article.ArticleType)
-		fmt.Fprintf(file, "Posted: %s\n", formatStringTimestamp(article.CreatedAt))
-		fmt.Fprintf(file, "Updated: %s\n", formatStringTimestamp(article.UpdatedAt))
-		fmt.Fprintf(file, "URL: https://leetcode.com/discuss/post/%d/%s/\n", article.TopicId, article.Slug)
-		fmt.Fprintf(file, "Author: %s\n", article.Author.UserName)
+		fmt.Fprintf(w, "UUID: %s\n", article.UUID)
+		fmt.Fprintf(w, "Title: %s\n", article.Title)
+		fmt.Fprintf(w, "Slug: %s\n", article.Slug)
+		fmt.Fprintf(w, "Article Type: %s\n", article.ArticleType)
+		fmt.Fprintf(w, "Posted: %s\n", formatStringTimestamp(article.CreatedAt, cfg.DateFormat.DateTime, cfg.Location))
+		fmt.Fprintf(w, "Updated: %s\n", formatStringTimestamp(article.UpdatedAt, cfg.DateFormat.DateTime, cfg.Location))
+		fmt.Fprintf(w, "URL: %s\n", articleURL(article))
+		fmt.Fprintf(w, "Author: %s\n", authorName(article, cfg))
 
 		// Summary
-		if article.Summary != "" {
-			fmt.Fprintf(file, "\n--- Summary ---\n")
-			fmt.Fprintf(file, "%s\n", article.Summary)
+		if summary := plainSummary(article.Summary, cfg.HTMLStripMode); summary != "" {
+			fmt.Fprintf(w, "\n--- Summary ---\n")
+			fmt.Fprintf(w, "%s\n", summary)
+		}
+
+		// Content
+		if article.Content != "" {
+			fmt.Fprintf(w, "\n--- Content ---\n")
+			fmt.Fprintf(w, "%s\n", strings.TrimSpace(article.Content))
+		}
+
+		// Stats, leaving out counts the API did not return
+		if article.ViewCount > 0 || article.CommentCount > 0 {
+			fmt.Fprintf(w, "\n--- Stats ---\n")
+			if article.ViewCount > 0 {
+				fmt.Fprintf(w, "Views: %d\n", article.ViewCount)
+			}
+			if article.CommentCount > 0 {
+				fmt.Fprintf(w, "Comments: %d\n", article.CommentCount)
+			}
 		}
 
 		// Tags
 		if len(article.Tags) > 0 {
-			fmt.Fprintf(file, "\n--- Tags ---\n")
-			for _, tag := range article.Tags {
-				fmt.Fprintf(file, "  - %s (%s) [%s]\n", tag.Name, tag.Slug, tag.TagType)
+			fmt.Fprintf(w, "\n--- Tags ---\n")
+			tags, hidden := visibleTags(article.Tags, cfg.MaxTagsShown)
+			for _, tag := range tags {
+				fmt.Fprintf(w, "  - %s (%s) [%s]\n", tag.Name, tag.Slug, tag.TagType)
+			}
+			if hidden > 0 {
+				fmt.Fprintf(w, "  +%d more\n", hidden)
 			}
 		}
 
 		// Reactions
 		if len(article.Reactions) > 0 {
-			fmt.Fprintf(file, "\n--- Reactions ---\n")
-			for _, reaction := range article.Reactions {
-				fmt.Fprintf(file, "  %s: %d\n", reaction.ReactionType, reaction.Count)
+			fmt.Fprintf(w, "\n--- Reactions ---\n")
+			for _, reaction := range mergeReactions(article.Reactions) {
+				fmt.Fprintf(w, "  %s\n", formatReaction(cfg.ReactionFormat, reaction))
 			}
 		}
 
-		fmt.Fprintf(file, "\n")
+		fmt.Fprintf(w, "\n")
 	}
+}
 
+// writeURLList writes the article URLs one per line, for bulk opening
+func writeURLList(w io.Writer, articles []Article) {
+	fmt.Fprintf(w, "--- URLs ---\n")
+	for _, article := range articles {
+		fmt.Fprintf(w, "%s\n", articleURL(article))
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// errWriter remembers the first write error so formatted output can be checked once
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	n, err := e.w.Write(p)
+	e.err = err
+	return n, err
+}
+
+// writeOutput creates filename with the content produced by write. When atomic is
+// set, the content goes to a temporary file in the same directory which is synced
+// and renamed over the target, so the target is never seen half-written. A named
+// pipe is written directly once a reader opens it, since neither truncating nor
+// replacing it makes sense.
+func writeOutput(filename string, atomic bool, write func(w io.Writer)) (err error) {
+	if pipe := isNamedPipe(filename); !atomic || pipe {
+		var file *os.File
+		var openErr error
+		if pipe {
+			file, openErr = openFIFO(filename, fifoOpenTimeout)
+		} else if file, openErr = os.Create(filename); openErr != nil {
+			openErr = fmt.Errorf("failed to create file: %w", openErr)
+		}
+		if openErr != nil {
+			return openErr
+		}
+		// A failed close can mean buffered data never reached the disk
+		defer closeWithError(file, &err)
+
+		ew := &errWriter{w: file}
+		write(ew)
+		return ew.err
+	}
+
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	ew := &errWriter{w: tmp}
+	write(ew)
+	err = ew.err
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, filename)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+
+	return syncDir(dir)
+}
+
+// closeWithError closes c, reporting a close failure through err unless an
+// earlier error is already set. Use it deferred with a named error result.
+func closeWithError(c io.Closer, err *error) {
+	if cerr := c.Close(); cerr != nil && *err == nil {
+		*err = fmt.Errorf("failed to close file: %w", cerr)
+	}
+}
+
+// syncDir flushes a directory entry change, such as a rename, to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory for sync: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory %s: %w", dir, err)
+	}
 	return nil
 }
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestRenderFilename(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 30, 15, 0, time.UTC)
+	tests := []struct {
+		template string
+		label    string
+		want     string
+	}{
+		{defaultFilenameTemplate, "", "leetcode_articles_2024-05-01_09-30-15.txt"},
+		{"digest-{20060102}-{count}.{ext}", "", "digest-20240501-7.txt"},
+		{labelledTemplate("lc_{2006-01-02}.{ext}"), "morning", "lc_2024-05-01_morning.txt"},
+	}
+	for _, tt := range tests {
+		got, err := renderFilename(tt.template, now, 7, "txt", tt.label)
+		if err != nil {
+			t.Errorf("renderFilename(%q): %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("renderFilename(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	for _, template := range []string{"../escape-{count}.txt", "{2006/01/02}.txt", ""} {
+		if name, err := renderFilename(template, now, 7, "txt", ""); err == nil {
+			t.Errorf("renderFilename(%q) = %q, want an unsafe name error", template, name)
+		}
+	}
+}
+
+func TestDailyFileMergesRuns(t *testing.T) {
+	t.Setenv("DAILY_FILE", "true")
+	cfg := testConfig(t)
+	inTempDir(t)
+
+	first := []Article{testArticle("u2", "2024-05-01T09:00:00Z"), testArticle("u1", "2024-05-01T08:00:00Z")}
+	filename, err := writeDigestFile(cfg, first, cfg.Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second run of the day repeats u2 and adds u3
+	second := []Article{testArticle("u3", "2024-05-01T12:00:00Z"), testArticle("u2", "2024-05-01T09:00:00Z")}
+	again, err := writeDigestFile(cfg, second, cfg.Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != filename {
+		t.Fatalf("second run wrote %s, want the day's file %s", again, filename)
+	}
+
+	content := readFile(t, filename)
+	if n := strings.Count(content, "UUID: u2\n"); n != 1 {
+		t.Errorf("u2 appears %d times, want once", n)
+	}
+	for _, want := range []string{"UUID: u1\n", "UUID: u3\n", "- 1 New Articles\n", "Article #3\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("merged file does not contain %q:\n%s", want, content)
+		}
+	}
+	if entries, _ := os.ReadDir("fetched_articles"); len(entries) != 1 {
+		t.Errorf("fetched_articles holds %d files, want 1", len(entries))
+	}
+}
+
+func TestAtomicWriteFailureKeepsTarget(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "digest.txt")
+	writeFile(t, target, "previous digest\n")
+
+	err := writeOutput(target, true, func(w io.Writer) {
+		fmt.Fprintf(w, "half of a new digest")
+		// Fail the temp file mid-write, as a full disk would
+		w.(*errWriter).err = errors.New("no space left on device")
+		fmt.Fprintf(w, " and the rest")
+	})
+	if err == nil || !strings.Contains(err.Error(), "no space left on device") {
+		t.Fatalf("writeOutput error = %v, want the injected write error", err)
+	}
+	if got := readFile(t, target); got != "previous digest\n" {
+		t.Errorf("target holds %q after the failed write, want the previous content", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want the temp file removed", len(entries))
+	}
+
+	// Without a previous file, the target is not created at all
+	fresh := filepath.Join(dir, "new.txt")
+	writeOutput(fresh, true, func(w io.Writer) {
+		w.(*errWriter).err = errors.New("no space left on device")
+	})
+	if _, err := os.Stat(fresh); !os.IsNotExist(err) {
+		t.Errorf("failed write created %s", fresh)
+	}
+}
+
+func TestAppendSkipsArticlesInFile(t *testing.T) {
+	cfg := testConfig(t)
+	filename := filepath.Join(t.TempDir(), "digest.txt")
+
+	existing := testArticle("u1", "2024-05-01T08:00:00Z")
+	existing.Summary = "Mentions another post:\nUUID: u9"
+	if err := writeArticlesToFile([]Article{existing}, filename, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// u1 is already in the file; u9 only appears inside its summary
+	added, err := appendArticlesToFile([]Article{
+		testArticle("u9", "2024-05-01T11:00:00Z"),
+		testArticle("u1", "2024-05-01T08:00:00Z"),
+		testArticle("u2", "2024-05-01T10:00:00Z"),
+	}, filename, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 {
+		t.Errorf("appended %d articles, want 2", added)
+	}
+
+	content := readFile(t, filename)
+	if n := strings.Count(content, "Title: Article u1\n"); n != 1 {
+		t.Errorf("u1 written %d times, want once", n)
+	}
+	for _, want := range []string{"- 2 New Articles\n", "Title: Article u9\n", "Title: Article u2\n", "Article #3\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("file does not contain %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestSeparatorWidth(t *testing.T) {
+	t.Setenv("SEPARATOR_WIDTH", "40")
+	cfg := testConfig(t)
+	filename := filepath.Join(t.TempDir(), "digest.txt")
+	if err := writeArticlesToFile([]Article{testArticle("u1", "2024-05-01T08:00:00Z")}, filename, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	separators := 0
+	for _, line := range strings.Split(readFile(t, filename), "\n") {
+		if line == "" || strings.Trim(line, "=═") != "" {
+			continue
+		}
+		separators++
+		if n := utf8.RuneCountInString(line); n != 40 {
+			t.Errorf("separator %q is %d wide, want 40", line, n)
+		}
+	}
+	if separators == 0 {
+		t.Error("file has no separators")
+	}
+
+	for _, width := range []string{"19", "201"} {
+		t.Setenv("SEPARATOR_WIDTH", width)
+		if _, err := loadConfig(); err == nil {
+			t.Errorf("SEPARATOR_WIDTH=%s accepted", width)
+		}
+	}
+}
+
+func TestFileGroupByDay(t *testing.T) {
+	t.Setenv("FILE_GROUP_BY", "day")
+	cfg := testConfig(t)
+	filename := filepath.Join(t.TempDir(), "digest.txt")
+
+	// Days are taken in IST, so 20:00 UTC already falls on the next day
+	articles := []Article{
+		testArticle("u1", "2024-05-01T20:00:00Z"), // 2 May 01:30 IST
+		testArticle("u2", "2024-05-01T10:00:00Z"), // 1 May 15:30 IST
+		testArticle("u3", "2024-04-30T19:00:00Z"), // 1 May 00:30 IST
+	}
+	if err := writeArticlesToFile(articles, filename, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, line := range strings.Split(readFile(t, filename), "\n") {
+		switch {
+		case strings.HasPrefix(line, "=== "):
+			order = append(order, line)
+		case strings.HasPrefix(line, "UUID: "):
+			order = append(order, strings.TrimPrefix(line, "UUID: "))
+		}
+	}
+	want := "=== 2024-05-02 ===,u1,=== 2024-05-01 ===,u2,u3"
+	if got := strings.Join(order, ","); got != want {
+		t.Errorf("file layout = %s, want %s", got, want)
+	}
+}
+
+func TestFileURLList(t *testing.T) {
+	articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z"), testArticle("u2", "2024-05-01T09:00:00Z")}
+	articles[1].TopicId = 2
+
+	for _, enabled := range []string{"false", "true"} {
+		t.Setenv("FILE_INCLUDE_URL_LIST", enabled)
+		cfg := testConfig(t)
+		filename := filepath.Join(t.TempDir(), "digest.txt")
+		if err := writeArticlesToFile(articles, filename, cfg); err != nil {
+			t.Fatal(err)
+		}
+		content := readFile(t, filename)
+
+		_, block, found := strings.Cut(content, "--- URLs ---\n")
+		if found != (enabled == "true") {
+			t.Fatalf("FILE_INCLUDE_URL_LIST=%s: URL block present = %v", enabled, found)
+		}
+		if !found {
+			continue
+		}
+		want := articleURL(articles[0]) + "\n" + articleURL(articles[1]) + "\n\n"
+		if block != want {
+			t.Errorf("URL block = %q, want %q", block, want)
+		}
+		for _, article := range articles {
+			if !strings.Contains(content, "URL: "+articleURL(article)+"\n") {
+				t.Errorf("article section URL for %s differs from the block", article.UUID)
+			}
+		}
+	}
+}
+
+// failingCloser reports err when closed
+type failingCloser struct{ err error }
+
+func (c failingCloser) Close() error { return c.err }
+
+func TestCloseWithError(t *testing.T) {
+	closeErr := errors.New("disk quota exceeded")
+	earlier := errors.New("write failed")
+	tests := []struct {
+		closer io.Closer
+		prior  error
+		want   error
+	}{
+		{failingCloser{nil}, nil, nil},
+		{failingCloser{closeErr}, nil, closeErr},
+		{failingCloser{closeErr}, earlier, earlier},
+	}
+	for _, tt := range tests {
+		err := tt.prior
+		closeWithError(tt.closer, &err)
+		if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("closeWithError(%v, %v) left %v, want %v", tt.closer, tt.prior, err, tt.want)
+		}
+	}
+}
+
+func TestWriteOutputReportsCloseFailure(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "digest.txt")
+	err := writeOutput(filename, false, func(w io.Writer) {
+		fmt.Fprintln(w, "header")
+		// Close the file under the writer so the deferred close fails
+		w.(*errWriter).w.(*os.File).Close()
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to close file") {
+		t.Errorf("writeOutput() = %v, want the close failure", err)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Article fields DEDUP_KEY can name as an article's identity
+const (
+	dedupUUID    = "uuid"
+	dedupTopicID = "topicId"
+	dedupSlug    = "slug"
+)
+
+// parseDedupKey validates a DEDUP_KEY value, ignoring case
+func parseDedupKey(v string) (string, error) {
+	for _, key := range []string{dedupUUID, dedupTopicID, dedupSlug} {
+		if strings.EqualFold(strings.TrimSpace(v), key) {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("unknown key %q: must be %s, %s or %s", v, dedupUUID, dedupTopicID, dedupSlug)
+}
+
+// articleKey returns the article's identity under the given DEDUP_KEY, or "" when
+// the field is not populated
+func articleKey(article Article, key string) string {
+	switch key {
+	case dedupTopicID:
+		if article.TopicId == 0 {
+			return ""
+		}
+		return strconv.Itoa(article.TopicId)
+	case dedupSlug:
+		return article.Slug
+	default:
+		return article.UUID
+	}
+}
+
+// warnMissingKeys logs how many articles lack the DEDUP_KEY field; those
+// articles are never treated as already seen
+func warnMissingKeys(articles []Article, key string) {
+	missing := 0
+	for _, article := range articles {
+		if articleKey(article, key) == "" {
+			missing++
+		}
+	}
+	if missing > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d of %d articles have no %s (DEDUP_KEY); they cannot be deduplicated\n",
+			missing, len(articles), key)
+	}
+}
+
+// excludeSeenKeys drops articles whose key is in seen, as well as repeats within
+// articles itself. Articles without the key are always kept.
+func excludeSeenKeys(articles []Article, seen map[string]bool, key string) []Article {
+	added := make(map[string]bool, len(articles))
+	var fresh []Article
+	for _, article := range articles {
+		k := articleKey(article, key)
+		if k != "" && (seen[k] || added[k]) {
+			continue
+		}
+		added[k] = true
+		fresh = append(fresh, article)
+	}
+	return fresh
+}
+
+// withLatest fills articles up to n with the ones in latest that it does not
+// already contain, flagged AlreadySeen, keeping the order of latest
+func withLatest(articles, latest []Article, n int, key string) []Article {
+	present := make(map[string]bool, len(articles))
+	for _, article := range articles {
+		present[articleKey(article, key)] = true
+	}
+
+	for _, article := range latest {
+		if len(articles) >= n {
+			break
+		}
+		if k := articleKey(article, key); k == "" || present[k] {
+			continue
+		}
+		article.AlreadySeen = true
+		articles = append(articles, article)
+	}
+	return articles
+}
+
+// newArticles returns the articles not flagged AlreadySeen
+func newArticles(articles []Article) []Article {
+	var fresh []Article
+	for _, article := range articles {
+		if !article.AlreadySeen {
+			fresh = append(fresh, article)
+		}
+	}
+	return fresh
+}
+
+// filterArticles applies the configured filters, keeping the original order
+func filterArticles(articles []Article, cfg Config) []Article {
+	if cfg.MinReactions > 0 {
+		articles = filterByMinReactions(articles, cfg.MinReactions)
+	}
+	if len(cfg.RequireReactionTypes) > 0 {
+		articles = filterByReactionTypes(articles, cfg.RequireReactionTypes)
+	}
+	if cfg.MaxPerAuthor > 0 {
+		articles = capPerAuthor(articles, cfg.MaxPerAuthor, cfg)
+	}
+	return articles
+}
+
+// capMostRecent keeps the n most recently created articles, in their current
+// order. Articles whose date cannot be parsed count as the oldest.
+func capMostRecent(articles []Article, n int) []Article {
+	if len(articles) <= n {
+		return articles
+	}
+	byAge := make([]int, len(articles))
+	created := make([]time.Time, len(articles))
+	for i, article := range articles {
+		byAge[i] = i
+		created[i], _ = parseArticleTime(article.CreatedAt)
+	}
+	sort.SliceStable(byAge, func(a, b int) bool {
+		return created[byAge[a]].After(created[byAge[b]])
+	})
+
+	keep := make([]bool, len(articles))
+	for _, i := range byAge[:n] {
+		keep[i] = true
+	}
+	kept := make([]Article, 0, n)
+	for i, article := range articles {
+		if keep[i] {
+			kept = append(kept, article)
+		}
+	}
+	return kept
+}
+
+// showingNote is " (showing N of M)" when MAX_ARTICLES trimmed the digest down
+// to shown articles, and empty otherwise
+func showingNote(shown int, cfg Config) string {
+	if cfg.MatchedArticles <= shown {
+		return ""
+	}
+	return fmt.Sprintf(" (showing %d of %d)", shown, cfg.MatchedArticles)
+}
+
+// filterByMinReactions keeps articles with at least min reactions in total
+func filterByMinReactions(articles []Article, min int) []Article {
+	var kept []Article
+	for _, article := range articles {
+		if totalReactions(article) >= min {
+			kept = append(kept, article)
+		}
+	}
+	return kept
+}
+
+// SORT_BY values
+const (
+	sortRecent    = "recent"    // the fetched order, newest first by default
+	sortReactions = "reactions" // ranks articles by total reactions
+)
+
+// parseSortBy validates a SORT_BY value; sortRecent is stored as ""
+func parseSortBy(v string) (string, error) {
+	switch s := strings.ToLower(strings.TrimSpace(v)); s {
+	case "", sortRecent:
+		return "", nil
+	case sortReactions:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown sort %q: must be %s or %s", v, sortRecent, sortReactions)
+	}
+}
+
+// sortArticles applies SORT_BY. It reorders the articles after they are fetched,
+// so the paging and its stop at the cutoff are unaffected. Ranking is stable,
+// so equally reacted articles keep their fetched order.
+func sortArticles(articles []Article, cfg Config) {
+	if cfg.SortBy != sortReactions {
+		return
+	}
+	sort.SliceStable(articles, func(i, j int) bool {
+		return totalReactions(articles[i]) > totalReactions(articles[j])
+	})
+}
+
+// rankLabel annotates an article's position when SORT_BY ranks them, such as
+// "#1 (312 reactions)". It is empty otherwise.
+func rankLabel(rank int, article Article, cfg Config) string {
+	if cfg.SortBy != sortReactions {
+		return ""
+	}
+	return fmt.Sprintf("#%d (%d reactions)", rank, totalReactions(article))
+}
+
+// capPerAuthor keeps at most limit articles by each author, dropping their least
+// reacted ones first (the older one on a tie), and logs what was dropped. Articles
+// without an author name cannot be attributed to anyone, so they are never capped.
+func capPerAuthor(articles []Article, limit int, cfg Config) []Article {
+	var authors []string
+	byAuthor := make(map[string][]int)
+	keep := make([]bool, len(articles))
+	for i, article := range articles {
+		name := article.Author.UserName
+		if name == "" {
+			keep[i] = true
+			continue
+		}
+		if _, ok := byAuthor[name]; !ok {
+			authors = append(authors, name)
+		}
+		byAuthor[name] = append(byAuthor[name], i)
+	}
+
+	for _, name := range authors {
+		indices := byAuthor[name]
+		if len(indices) > limit {
+			// Stable, so equally reacted articles keep their newest-first order
+			sort.SliceStable(indices, func(a, b int) bool {
+				return totalReactions(articles[indices[a]]) > totalReactions(articles[indices[b]])
+			})
+			fmt.Printf("Dropped %d articles by %s (MAX_PER_AUTHOR=%d)\n",
+				len(indices)-limit, authorName(articles[indices[0]], cfg), limit)
+			indices = indices[:limit]
+		}
+		for _, i := range indices {
+			keep[i] = true
+		}
+	}
+
+	var capped []Article
+	for i, article := range articles {
+		if keep[i] {
+			capped = append(capped, article)
+		}
+	}
+	return capped
+}
+
+// filterByReactionTypes keeps articles with at least one non-zero reaction of the given types
+func filterByReactionTypes(articles []Article, types []string) []Article {
+	wanted := make(map[string]bool, len(types))
+	for _, t := range types {
+		wanted[normalizeReactionType(t)] = true
+	}
+
+	var filtered []Article
+	for _, article := range articles {
+		for _, reaction := range article.Reactions {
+			if reaction.Count > 0 && wanted[normalizeReactionType(reaction.ReactionType)] {
+				filtered = append(filtered, article)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// normalizeReactionType converts a reaction type such as "thumbs-down" to its API form "THUMBS_DOWN"
+func normalizeReactionType(t string) string {
+	t = strings.ToUpper(strings.TrimSpace(t))
+	return strings.NewReplacer("-", "_", " ", "_").Replace(t)
+}
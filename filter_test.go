@@ -0,0 +1,264 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// uuidsOf lists the UUIDs of articles, in order, for comparing results
+func uuidsOf(articles []Article) string {
+	uuids := make([]string, len(articles))
+	for i, article := range articles {
+		uuids[i] = article.UUID
+	}
+	return strings.Join(uuids, ",")
+}
+
+func TestFilterByReactionTypes(t *testing.T) {
+	withReactions := func(uuid string, reactions ...Reaction) Article {
+		article := testArticle(uuid, "2024-05-01T10:00:00Z")
+		article.Reactions = reactions
+		return article
+	}
+	articles := []Article{
+		withReactions("upvoted", Reaction{Count: 5, ReactionType: "UPVOTE"}),
+		withReactions("starred", Reaction{Count: 1, ReactionType: "UPVOTE"}, Reaction{Count: 2, ReactionType: "STAR"}),
+		withReactions("bookmarked", Reaction{Count: 3, ReactionType: "BOOKMARK"}),
+		withReactions("zero-star", Reaction{Count: 0, ReactionType: "STAR"}),
+		withReactions("thumbs-down", Reaction{Count: 1, ReactionType: "THUMBS_DOWN"}),
+		withReactions("none"),
+	}
+
+	tests := []struct {
+		types []string
+		want  string
+	}{
+		{[]string{"STAR"}, "starred"},
+		{[]string{"star", "Bookmark"}, "starred,bookmarked"},
+		{[]string{"thumbs-down"}, "thumbs-down"},
+		{[]string{"CONFUSED"}, ""},
+	}
+	for _, tt := range tests {
+		if got := uuidsOf(filterByReactionTypes(articles, tt.types)); got != tt.want {
+			t.Errorf("filterByReactionTypes(%v) = %q, want %q", tt.types, got, tt.want)
+		}
+	}
+}
+
+func TestCapPerAuthorSkipsUnnamed(t *testing.T) {
+	cfg := testConfig(t)
+	articles := []Article{
+		testArticle("u1", "2024-05-01T10:00:00Z"),
+		testArticle("u2", "2024-05-01T09:00:00Z"),
+		testArticle("u3", "2024-05-01T08:00:00Z"),
+		testArticle("u4", "2024-05-01T07:00:00Z"),
+	}
+	articles[0].Author.UserName = "alice"
+	articles[1].Author.UserName = ""
+	articles[2].Author.UserName = ""
+	articles[3].Author.UserName = "alice"
+
+	if got := uuidsOf(capPerAuthor(articles, 1, cfg)); got != "u1,u2,u3" {
+		t.Errorf("capPerAuthor() kept %s, want u1,u2,u3", got)
+	}
+}
+
+func TestMaxPerAuthor(t *testing.T) {
+	t.Setenv("MAX_PER_AUTHOR", "2")
+	cfg := testConfig(t)
+
+	// alice posted four articles; her two most reacted stay, and on the tie
+	// between a3 and a4 the newer a3 wins
+	reactions := map[string]int{"a1": 1, "a2": 9, "a3": 4, "a4": 4, "b1": 0}
+	var articles []Article
+	for i, uuid := range []string{"a1", "b1", "a2", "a3", "a4"} {
+		article := testArticle(uuid, time.Date(2024, 5, 1, 12-i, 0, 0, 0, time.UTC).Format(time.RFC3339))
+		article.Author.UserName = "alice"
+		if uuid[0] == 'b' {
+			article.Author.UserName = "bob"
+		}
+		article.Reactions = []Reaction{{ReactionType: "UPVOTE", Count: reactions[uuid]}}
+		articles = append(articles, article)
+	}
+
+	if got := uuidsOf(filterArticles(articles, cfg)); got != "b1,a2,a3" {
+		t.Errorf("kept %s, want b1,a2,a3 in their original order", got)
+	}
+}
+
+func TestDedupKey(t *testing.T) {
+	article := func(uuid string, topicID int, slug string) Article {
+		a := testArticle(uuid, "2024-05-01T10:00:00Z")
+		a.TopicId, a.Slug = topicID, slug
+		return a
+	}
+	// b shares a's slug, c shares a's topic and d has neither a topic nor a slug
+	articles := []Article{article("a", 1, "s1"), article("b", 2, "s1"), article("c", 1, "s3"), article("d", 0, "")}
+
+	tests := []struct {
+		value       string
+		seen        []string
+		want        string
+		wantMissing int
+	}{
+		{"", []string{"b"}, "a,c,d", 0},
+		{"UUID", []string{"b"}, "a,c,d", 0},
+		{"topicid", nil, "a,b,d", 1},
+		{"topicId", []string{"2"}, "a,d", 1},
+		{" slug ", []string{"s3"}, "a,d", 1},
+	}
+	for _, tt := range tests {
+		t.Setenv("DEDUP_KEY", tt.value)
+		key := testConfig(t).DedupKey
+
+		missing := 0
+		for _, a := range articles {
+			if articleKey(a, key) == "" {
+				missing++
+			}
+		}
+		if missing != tt.wantMissing {
+			t.Errorf("DEDUP_KEY=%q: %d articles without a key, want %d", tt.value, missing, tt.wantMissing)
+		}
+		seen := State{SeenUUIDs: tt.seen}.seen()
+		if got := uuidsOf(excludeSeenKeys(articles, seen, key)); got != tt.want {
+			t.Errorf("DEDUP_KEY=%q: kept %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	t.Setenv("DEDUP_KEY", "id")
+	if _, err := loadConfig(); err == nil {
+		t.Error("DEDUP_KEY=id was accepted")
+	}
+}
+
+func TestRankLabels(t *testing.T) {
+	article := func(uuid string, reactions ...int) Article {
+		a := testArticle(uuid, "2024-05-01T10:00:00Z")
+		for _, count := range reactions {
+			a.Reactions = append(a.Reactions, Reaction{Count: count, ReactionType: "UPVOTE"})
+		}
+		return a
+	}
+	rankDiv := regexp.MustCompile(`<div class="article-rank">([^<]*)</div>`)
+
+	tests := []struct {
+		sortBy    string
+		wantFile  []string
+		wantEmail []string
+	}{
+		{
+			sortBy:   "",
+			wantFile: []string{"Article #1", "Article #2", "Article #3"},
+		},
+		{
+			sortBy:    "reactions",
+			wantFile:  []string{"Article #1 - #1 (312 reactions)", "Article #2 - #2 (40 reactions)", "Article #3 - #3 (5 reactions)"},
+			wantEmail: []string{"#1 (312 reactions)", "#2 (40 reactions)", "#3 (5 reactions)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run("SORT_BY="+tt.sortBy, func(t *testing.T) {
+			t.Setenv("SORT_BY", tt.sortBy)
+			cfg := testConfig(t)
+			articles := []Article{article("low", 5), article("top", 300, 12), article("mid", 40)}
+			sortArticles(articles, cfg)
+
+			filename := filepath.Join(t.TempDir(), "digest.txt")
+			if err := writeArticlesToFile(articles, filename, cfg); err != nil {
+				t.Fatal(err)
+			}
+			var headers []string
+			for _, line := range strings.Split(readFile(t, filename), "\n") {
+				if strings.HasPrefix(line, "Article #") {
+					headers = append(headers, line)
+				}
+			}
+			if got, want := strings.Join(headers, "\n"), strings.Join(tt.wantFile, "\n"); got != want {
+				t.Errorf("file headers:\n%s\nwant:\n%s", got, want)
+			}
+
+			var labels []string
+			for _, m := range rankDiv.FindAllStringSubmatch(generateHTMLEmail(articles, cfg.Location, cfg), -1) {
+				labels = append(labels, m[1])
+			}
+			if got, want := strings.Join(labels, "; "), strings.Join(tt.wantEmail, "; "); got != want {
+				t.Errorf("email ranks %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRankLabelsWhenAppending(t *testing.T) {
+	t.Setenv("SORT_BY", "reactions")
+	cfg := testConfig(t)
+	filename := filepath.Join(t.TempDir(), "digest.txt")
+	if err := writeArticlesToFile([]Article{testArticle("u1", "2024-05-01T08:00:00Z")}, filename, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Appended sections continue the numbering, and the rank follows it
+	appended := []Article{testArticle("u2", "2024-05-01T10:00:00Z"), testArticle("u3", "2024-05-01T09:00:00Z")}
+	appended[0].Reactions = []Reaction{{Count: 9, ReactionType: "UPVOTE"}}
+	if _, err := appendArticlesToFile(appended, filename, cfg); err != nil {
+		t.Fatal(err)
+	}
+	var headers []string
+	for _, line := range strings.Split(readFile(t, filename), "\n") {
+		if strings.HasPrefix(line, "Article #") {
+			headers = append(headers, line)
+		}
+	}
+	want := []string{"Article #1 - #1 (0 reactions)", "Article #2 - #2 (9 reactions)", "Article #3 - #3 (0 reactions)"}
+	if got := strings.Join(headers, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("file headers:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestSortAndMinReactions(t *testing.T) {
+	article := func(uuid string, reactions ...int) Article {
+		a := testArticle(uuid, "2024-05-01T10:00:00Z")
+		for _, count := range reactions {
+			a.Reactions = append(a.Reactions, Reaction{Count: count, ReactionType: "UPVOTE"})
+		}
+		return a
+	}
+	// Fetched newest first: none has no reactions, tie1 and tie2 both total 10
+	fetched := []Article{article("none"), article("tie1", 4, 6), article("top", 50), article("tie2", 10), article("low", 2)}
+
+	tests := []struct {
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{nil, "none,tie1,top,tie2,low", false},
+		{[]string{"-sort", "recent"}, "none,tie1,top,tie2,low", false},
+		{[]string{"-sort", "reactions"}, "top,tie1,tie2,low,none", false},
+		{[]string{"-min-reactions", "10"}, "tie1,top,tie2", false},
+		{[]string{"-sort", "reactions", "-min-reactions", "3"}, "top,tie1,tie2", false},
+		{[]string{"-sort", "popular"}, "", true},
+		{[]string{"-min-reactions", "-1"}, "", true},
+	}
+	for _, tt := range tests {
+		cfg, err := loadCommandConfig("test", tt.args, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: error = %v, want error %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		articles := filterArticles(append([]Article(nil), fetched...), cfg)
+		sortArticles(articles, cfg)
+		if got := uuidsOf(articles); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.args, got, tt.want)
+		}
+	}
+
+	if got := totalReactions(article("sum", 4, 6, 0)); got != 10 {
+		t.Errorf("totalReactions() = %d, want 10", got)
+	}
+}
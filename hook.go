@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// runPostRunHook runs the POST_RUN_HOOK shell command after a successful run.
+// The output file path and article count are passed as $1 and $2 and as the
+// LCDIGEST_OUTPUT_FILE and LCDIGEST_ARTICLE_COUNT environment variables.
+// A failing hook only logs a warning unless POST_RUN_HOOK_FATAL is set.
+// It returns the process exit code.
+func runPostRunHook(cfg Config, outputFile string, count int) int {
+	if cfg.PostRunHook == "" {
+		return 0
+	}
+
+	fmt.Println("\nRunning post-run hook...")
+	cmd := exec.Command("sh", "-c", cfg.PostRunHook, "post-run-hook", outputFile, strconv.Itoa(count))
+	cmd.Env = append(os.Environ(),
+		"LCDIGEST_OUTPUT_FILE="+outputFile,
+		"LCDIGEST_ARTICLE_COUNT="+strconv.Itoa(count),
+	)
+
+	out, err := cmd.CombinedOutput()
+	if output := strings.TrimRight(string(out), "\n"); output != "" {
+		for _, line := range strings.Split(output, "\n") {
+			fmt.Printf("   [hook] %s\n", line)
+		}
+	}
+
+	if err != nil {
+		if cfg.PostRunHookFatal {
+			fmt.Fprintf(os.Stderr, "Error: Post-run hook failed: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Warning: Post-run hook failed: %v\n", err)
+		return 0
+	}
+
+	fmt.Println("✓ Post-run hook completed")
+	return 0
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestPostRunHook(t *testing.T) {
+	inTempDir(t)
+
+	cfg := Config{PostRunHook: `echo "$1 $2 $LCDIGEST_OUTPUT_FILE $LCDIGEST_ARTICLE_COUNT" > hook.out`}
+	if code := runPostRunHook(cfg, "fetched_articles/digest.txt", 4); code != 0 {
+		t.Fatalf("runPostRunHook() = %d, want 0", code)
+	}
+	want := "fetched_articles/digest.txt 4 fetched_articles/digest.txt 4\n"
+	if got := readFile(t, "hook.out"); got != want {
+		t.Errorf("hook saw %q, want %q", got, want)
+	}
+}
+
+func TestPostRunHookFailure(t *testing.T) {
+	tests := []struct {
+		fatal bool
+		want  int
+	}{
+		{false, 0},
+		{true, 1},
+	}
+	for _, tt := range tests {
+		cfg := Config{PostRunHook: "exit 3", PostRunHookFatal: tt.fatal}
+		if got := runPostRunHook(cfg, "", 0); got != tt.want {
+			t.Errorf("fatal=%v: runPostRunHook() = %d, want %d", tt.fatal, got, tt.want)
+		}
+	}
+}
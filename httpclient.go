@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// Doer sends an HTTP request. *http.Client satisfies it; tests can substitute one
+// that talks to an httptest.Server or answers requests itself.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// requestTimeout bounds each request made with defaultHTTPClient
+const requestTimeout = 15 * time.Second
+
+// defaultHTTPClient is used wherever no Doer is configured
+var defaultHTTPClient Doer = &http.Client{Timeout: requestTimeout}
+
+// httpClient returns client, or defaultHTTPClient when it is nil
+func httpClient(client Doer) Doer {
+	if client == nil {
+		return defaultHTTPClient
+	}
+	return client
+}
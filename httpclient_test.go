@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// respond builds a response with the given status and body
+func respond(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestDefaultHTTPClient(t *testing.T) {
+	client, ok := httpClient(nil).(*http.Client)
+	if !ok || client.Timeout != requestTimeout || requestTimeout.Seconds() != 15 {
+		t.Errorf("default client %#v, want an *http.Client with a 15s timeout", httpClient(nil))
+	}
+	custom := doerFunc(func(*http.Request) (*http.Response, error) { return nil, nil })
+	if _, ok := httpClient(custom).(doerFunc); !ok {
+		t.Error("a configured Doer was not used")
+	}
+}
+
+func TestGraphQLThroughDoer(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantCount  int
+		wantStatus int
+	}{
+		{"listing", http.StatusOK, string(listingJSON(250, []Article{testArticle("u1", "2024-05-01T10:00:00Z")})), 1, 0},
+		{"server error", http.StatusServiceUnavailable, "upstream down", 0, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			var body graphQLRequest
+			doer := doerFunc(func(r *http.Request) (*http.Response, error) {
+				got = r
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("request body is not JSON: %v", err)
+				}
+				return respond(tt.status, tt.body), nil
+			})
+
+			articles, total, err := fetchDiscussArticlesWithSkip(context.Background(), batchSize, 200, clientOptions{HTTPClient: doer, UserAgent: "test-agent"})
+			if got.Method != http.MethodPost || got.URL.String() != leetcodeGraphQLURL {
+				t.Errorf("request %s %s, want POST %s", got.Method, got.URL, leetcodeGraphQLURL)
+			}
+			if got.Header.Get("Content-Type") != "application/json" || got.Header.Get("User-Agent") != "test-agent" {
+				t.Errorf("headers %v", got.Header)
+			}
+			if body.Variables["first"] != float64(batchSize) || body.Variables["skip"] != float64(200) || !strings.Contains(body.Query, "ugcArticleDiscussionArticles") {
+				t.Errorf("request variables %v", body.Variables)
+			}
+
+			if tt.wantStatus == 0 {
+				if err != nil || len(articles) != tt.wantCount || total != 250 {
+					t.Errorf("got %d articles of %d, %v", len(articles), total, err)
+				}
+				return
+			}
+			var fetchErr *FetchError
+			if !errors.As(err, &fetchErr) || fetchErr.StatusCode != tt.wantStatus || fetchErr.Skip != 200 || fetchErr.Body != tt.body {
+				t.Errorf("error = %#v, want a FetchError for status %d at skip 200 with the body", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSendGridThroughDoer(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"accepted", http.StatusAccepted, "", ""},
+		{"rejected", http.StatusBadRequest, `{"errors": [{"message": "The from address does not match a verified Sender Identity"}]}`,
+			"sendgrid API returned status 400: The from address does not match a verified Sender Identity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			var payload SendGridEmail
+			doer := doerFunc(func(r *http.Request) (*http.Response, error) {
+				got = r
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("request body is not JSON: %v", err)
+				}
+				return respond(tt.status, tt.body), nil
+			})
+
+			msg := emailMessage{FromEmail: "bot@example.com", Subject: "Digest", Text: "hi", HTML: "<p>hi</p>"}
+			err := sendEmailViaSendGrid(context.Background(), doer, "sg-key", msg, []string{"a@example.com"}, sendGridMaxRecipients)
+			if got.URL.String() != sendGridAPIURL || got.Header.Get("Authorization") != "Bearer sg-key" {
+				t.Errorf("request to %s with Authorization %q", got.URL, got.Header.Get("Authorization"))
+			}
+			if payload.Subject != "Digest" || payload.From.Email != "bot@example.com" || payload.Personalizations[0].To[0].Email != "a@example.com" {
+				t.Errorf("payload %+v", payload)
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var emailErr *EmailError
+			if !errors.As(err, &emailErr) || err.Error() != tt.wantErr {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
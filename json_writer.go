@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// writeArticlesToJSON writes the articles as an indented JSON array that
+// readArticlesJSON (and so the send command and file source) can read back.
+// Timestamps keep their original RFC3339 strings.
+func writeArticlesToJSON(articles []Article, filename string, cfg Config) error {
+	data, err := json.MarshalIndent(exportArticles(articles, cfg), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode articles: %w", err)
+	}
+
+	return writeOutput(filename, cfg.AtomicWrite, func(w io.Writer) {
+		w.Write(data)
+		w.Write([]byte("\n"))
+	})
+}
+
+// appendArticlesToJSON merges articles into an existing JSON digest, skipping UUIDs
+// it already holds and keeping the newest first. A missing file is written in full.
+// It returns the number of articles added.
+func appendArticlesToJSON(articles []Article, filename string, cfg Config) (int, error) {
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return len(articles), writeArticlesToJSON(articles, filename, cfg)
+	}
+
+	existing, err := readArticlesJSON(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	seen := make(map[string]bool, len(existing))
+	for _, article := range existing {
+		seen[article.UUID] = true
+	}
+	fresh := excludeSeenArticles(articles, seen)
+	if len(fresh) == 0 {
+		return 0, nil
+	}
+
+	merged := append(append([]Article{}, fresh...), existing...)
+	return len(fresh), writeArticlesToJSON(merged, filename, cfg)
+}
+
+// exportArticles prepares articles for structured output. Authors are replaced
+// with their pseudonyms when ANONYMIZE_AUTHORS is set; everything else, including
+// every tag, is kept as fetched.
+func exportArticles(articles []Article, cfg Config) []Article {
+	exported := make([]Article, len(articles))
+	for i, article := range articles {
+		article.Author.UserName = authorName(article, cfg)
+		exported[i] = article
+	}
+	return exported
+}
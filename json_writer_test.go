@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	cfg := testConfig(t)
+	filename := filepath.Join(t.TempDir(), "digest.json")
+
+	article := testArticle("u1", "2024-05-01T10:00:00.123456+05:30")
+	article.UpdatedAt = "2024-05-01T10:05:00Z"
+	article.Summary = `Uses <b>tags</b> & "quotes"`
+	article.Tags = []Tag{{Name: "Graph", Slug: "graph", TagType: "TOPIC"}}
+	article.Reactions = []Reaction{{ReactionType: "UPVOTE", Count: 3}}
+	articles := []Article{article, testArticle("u2", "2024-05-01T09:00:00Z")}
+
+	if err := writeArticlesToJSON(articles, filename, cfg); err != nil {
+		t.Fatal(err)
+	}
+	var got []Article
+	if err := json.Unmarshal([]byte(readFile(t, filename)), &got); err != nil {
+		t.Fatalf("output is not a JSON array of articles: %v", err)
+	}
+	if !reflect.DeepEqual(got, articles) {
+		t.Errorf("round trip changed the articles:\ngot  %+v\nwant %+v", got, articles)
+	}
+}
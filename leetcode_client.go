@@ -2,15 +2,27 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
 	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 )
 
-const (
-	leetcodeGraphQLURL = "https://leetcode.com/graphql"
-	discussTopicsQuery = `
+const leetcodeGraphQLURL = "https://leetcode.com/graphql"
+
+// discussQueryTemplate is the article list query; %s receives the node fields
+const discussQueryTemplate = `
 		query discussPostItems($orderBy: ArticleOrderByEnum, $keywords: [String]!, $tagSlugs: [String!], $skip: Int, $first: Int) {
 			ugcArticleDiscussionArticles(
 				orderBy: $orderBy
@@ -21,7 +33,15 @@ const (
 			) {
 				totalNum
 				edges {
-					node {
+					node {%s
+					}
+				}
+			}
+		}
+	`
+
+// coreQueryFields are always selected; the rest of the tool relies on them
+const coreQueryFields = `
 						uuid
 						topicId
 						title
@@ -32,116 +52,725 @@ const (
 						}
 						createdAt
 						updatedAt
-						articleType
+						articleType`
+
+// optionalQueryFields are selections that can be dropped with DISABLE_FIELDS, in
+// query order, so the core fetch keeps working if LeetCode removes one of them
+var optionalQueryFields = []struct {
+	name      string
+	selection string
+}{
+	{"thumbnail", `
+						thumbnail`},
+	{"tags", `
 						tags {
 							name
 							slug
 							tagType
-						}
+						}`},
+	{"reactions", `
 						reactions {
 							count
 							reactionType
-						}
-					}
-				}
+						}`},
+	{"views", `
+						viewCount`},
+	{"comments", `
+						commentCount`},
+}
+
+// buildDiscussQuery assembles the article query from the core fields plus every
+// optional field not listed in disabled
+func buildDiscussQuery(disabled map[string]bool) string {
+	fields := coreQueryFields
+	for _, f := range optionalQueryFields {
+		if !disabled[f.name] {
+			fields += f.selection
+		}
+	}
+	return fmt.Sprintf(discussQueryTemplate, fields)
+}
+
+// parseDisabledFields validates a DISABLE_FIELDS list against the optional fields
+func parseDisabledFields(names []string) (map[string]bool, error) {
+	known := allOptionalFields()
+	disabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if !known[name] {
+			var valid []string
+			for _, f := range optionalQueryFields {
+				valid = append(valid, f.name)
 			}
+			return nil, fmt.Errorf("unknown field %q: must be one of %s", name, strings.Join(valid, ", "))
 		}
-	`
+		disabled[name] = true
+	}
+	return disabled, nil
+}
+
+// tagSlugPattern matches the form of LeetCode tag slugs, such as "dynamic-programming"
+var tagSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// parseTagSlugs splits a comma-separated tag list. Empty entries and strings that
+// cannot be tag slugs are dropped with a warning rather than sent to the API.
+func parseTagSlugs(list string) []string {
+	var slugs []string
+	for _, slug := range strings.Split(list, ",") {
+		slug = strings.ToLower(strings.TrimSpace(slug))
+		if slug == "" {
+			continue
+		}
+		if !tagSlugPattern.MatchString(slug) {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring invalid tag slug %q\n", slug)
+			continue
+		}
+		slugs = append(slugs, slug)
+	}
+	return slugs
+}
+
+// parseKeywords splits a keyword list on spaces and commas
+func parseKeywords(list string) []string {
+	return strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// batchSize is the number of articles requested per page
+const batchSize = 100
+
+// Concurrent page requests made by a scan, unless FETCH_WORKERS says otherwise.
+// The cap keeps a misconfigured run from flooding LeetCode.
+const (
+	defaultFetchWorkers = 4
+	maxFetchWorkers     = 8
+)
+
+// Default request headers, overridable through configuration
+const (
+	defaultReferer = "https://leetcode.com/discuss/"
+	defaultOrigin  = "https://leetcode.com"
 )
 
-// fetchArticlesAfterTime fetches all articles published after the given cutoff time using pagination
-func fetchArticlesAfterTime(cutoffTime time.Time) ([]Article, error) {
+// defaultUserAgent identifies this tool to LeetCode unless USER_AGENT overrides it
+var defaultUserAgent = "LeetCode-Discuss-Fetcher/" + appVersion
+
+// clientOptions controls how requests to LeetCode are made
+type clientOptions struct {
+	UserAgent string
+	Referer   string
+	Origin    string
+	// SessionCookie is the LEETCODE_SESSION cookie value, sent when set
+	SessionCookie string
+
+	// LightQuery omits all optional fields during the cutoff scan
+	LightQuery bool
+	// RefetchFullDetails re-fetches complete articles after a light scan
+	RefetchFullDetails bool
+
+	// PageDelay is slept before every page request after the first. Pages
+	// requested at once each wait it, so Limiter is what paces them as a whole.
+	PageDelay time.Duration
+	// MaxPages caps how many pages a scan requests; 0 means no limit
+	MaxPages int
+	// Workers is how many pages a scan requests at once after the first
+	Workers int
+
+	// DisabledFields names optional query fields that are never requested
+	DisabledFields map[string]bool
+
+	// Limiter paces every request made with these options; nil means unlimited
+	Limiter *rateLimiter
+	// HTTPClient sends the requests; nil means defaultHTTPClient
+	HTTPClient Doer
+
+	// PersistedQuery sends a query hash in place of the listing query; nil sends the query
+	PersistedQuery *persistedQuery
+
+	// TagSlugs limits results to articles with any of these tags
+	TagSlugs []string
+	// Keywords limits results to articles matching these search terms
+	Keywords []string
+
+	// OrderBy is the ArticleOrderByEnum value to list by; empty means orderMostRecent
+	OrderBy string
+
+	// MaxRetries is how many times a transient request failure is retried, waiting
+	// RetryBaseDelay before the first retry and doubling it after each one
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+}
+
+// Article orderings accepted by ORDER_BY
+const (
+	orderMostRecent = "MOST_RECENT"
+	orderMostVotes  = "MOST_VOTES"
+	orderHottest    = "HOTTEST"
+)
+
+// defaultRankedPages caps the scan when ordering by anything but recency and
+// MAX_PAGES is not set, since such a scan cannot stop at the cutoff
+const defaultRankedPages = 10
+
+// orderBy returns the ordering to request
+func (o clientOptions) orderBy() string {
+	if o.OrderBy == "" {
+		return orderMostRecent
+	}
+	return o.OrderBy
+}
+
+// chronological reports whether results arrive newest first, which is what lets a
+// scan stop at the first article older than the cutoff
+func (o clientOptions) chronological() bool {
+	return o.orderBy() == orderMostRecent
+}
+
+// waitBeforePage throttles page requests according to PageDelay
+func (o clientOptions) waitBeforePage(ctx context.Context, skip int) error {
+	if skip > 0 && o.PageDelay > 0 {
+		return sleepContext(ctx, o.PageDelay)
+	}
+	return ctx.Err()
+}
+
+// persistedQuery is an automatic persisted query (APQ) hash that listing requests
+// send instead of the query text. Once the server answers that it does not know
+// the hash, every later request of the run sends the full query.
+type persistedQuery struct {
+	Hash     string
+	notFound atomic.Bool
+}
+
+// use reports whether requests should send the hash
+func (p *persistedQuery) use() bool {
+	return p != nil && p.Hash != "" && !p.notFound.Load()
+}
+
+// persistedQueryNotFound reports whether a GraphQL error says the server has no
+// query stored for the hash sent
+func persistedQueryNotFound(errs []GraphQLError) bool {
+	for _, e := range errs {
+		if e.Message == "PersistedQueryNotFound" || e.Extensions.Code == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+	return false
+}
+
+// query returns the GraphQL query matching the selected mode
+func (o clientOptions) query() string {
+	if o.LightQuery {
+		return buildDiscussQuery(allOptionalFields())
+	}
+	return buildDiscussQuery(o.DisabledFields)
+}
+
+// allOptionalFields disables every optional field, leaving only the core selection
+func allOptionalFields() map[string]bool {
+	all := make(map[string]bool, len(optionalQueryFields))
+	for _, f := range optionalQueryFields {
+		all[f.name] = true
+	}
+	return all
+}
+
+// setHeaders applies the standard header set to a LeetCode request
+func (o clientOptions) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", o.UserAgent)
+	req.Header.Set("Referer", o.Referer)
+	req.Header.Set("Origin", o.Origin)
+	if o.SessionCookie != "" {
+		req.Header.Set("Cookie", "LEETCODE_SESSION="+o.SessionCookie)
+	}
+}
+
+// fetchArticlesAfterTime fetches all articles published after the given cutoff time using pagination.
+// With the default MOST_RECENT order it stops at the first article older than the cutoff.
+// Any other order says nothing about age, so every page up to the page cap is scanned
+// and filtered by date, and the API's order is kept. When a page fails, the articles
+// collected from earlier pages are returned along with the error.
+func fetchArticlesAfterTime(ctx context.Context, cutoffTime time.Time, opts clientOptions) ([]Article, error) {
+	return scanArticles(ctx, cutoffTime, 0, opts)
+}
+
+// fetchLatestArticles fetches the first n articles in the configured order,
+// regardless of when they were published
+func fetchLatestArticles(ctx context.Context, n int, opts clientOptions) ([]Article, error) {
+	return scanArticles(ctx, time.Time{}, n, opts)
+}
+
+// scanArticles pages through the listing as described for fetchArticlesAfterTime,
+// additionally stopping once limit articles are collected when limit is positive.
+// After the first page, up to opts.Workers pages are requested at once and then
+// processed in offset order, so a round may fetch a few pages past the cutoff but
+// the result is the same as a sequential scan.
+func scanArticles(ctx context.Context, cutoffTime time.Time, limit int, opts clientOptions) ([]Article, error) {
 	var allArticles []Article
-	batchSize := 100
-	skip := 0
+	seen := make(map[string]bool)
+	skip, stride, totalNum := 0, batchSize, 0
+
+	chronological := opts.chronological()
+	maxPages := opts.MaxPages
+	if !chronological && maxPages == 0 {
+		maxPages = defaultRankedPages
+	}
+
+	for page := 1; ; {
+		if maxPages > 0 && page > maxPages {
+			if chronological {
+				fmt.Fprintf(os.Stderr, "Warning: stopped after %d pages (MAX_PAGES); older articles were not fetched\n", maxPages)
+			} else {
+				fmt.Printf("Scanned %d pages ordered by %s.\n", maxPages, opts.orderBy())
+			}
+			break
+		}
+
+		// The first page reveals the total, so only later rounds run concurrently
+		n := 1
+		if page > 1 {
+			n = max(opts.Workers, 1)
+			if maxPages > 0 {
+				n = min(n, maxPages-page+1)
+			}
+			if totalNum > 0 {
+				n = max(min(n, (totalNum-skip+stride-1)/stride), 1)
+			}
+		}
+
+		done := false
+		for _, result := range fetchPages(ctx, skip, stride, n, opts) {
+			page++
+			if result.err != nil {
+				return allArticles, result.err
+			}
+			batch := result.articles
+			totalNum = result.totalNum
+
+			if len(batch) == 0 {
+				if result.offset+stride < totalNum {
+					// A page can come back empty (e.g. deleted articles) while more
+					// exist further on, so move past it instead of stopping
+					skip = result.offset + stride
+					continue
+				}
+				done = true // No more articles
+				break
+			}
+			if result.offset == 0 && len(batch) < batchSize && len(batch) < totalNum {
+				// The server caps the page size below batchSize; later rounds
+				// request offsets that far apart so no article is skipped
+				stride = len(batch)
+			}
+
+			foundOlderArticle := false
+			for _, article := range batch {
+				articleTime, err := parseArticleTime(article.CreatedAt)
+				if err != nil {
+					continue // Skip if we can't parse the time
+				}
+
+				if articleTime.After(cutoffTime) {
+					// Pages requested at once may overlap if the listing shifts.
+					// Articles without a UUID cannot be matched, so they are all kept.
+					if article.UUID == "" || !seen[article.UUID] {
+						seen[article.UUID] = true
+						allArticles = append(allArticles, article)
+					}
+				} else if chronological {
+					foundOlderArticle = true
+					break
+				}
+			}
+
+			if foundOlderArticle {
+				done = true // Stop once we reach articles at or before the cutoff
+				break
+			}
+			if limit > 0 && len(allArticles) >= limit {
+				allArticles = allArticles[:limit]
+				done = true
+				break
+			}
 
-	for {
-		fmt.Printf("Fetching batch starting at offset %d...\n", skip)
+			// Advance by what was actually returned: the server may cap the page size
+			// below batchSize, so a short page alone does not mean the end of the data
+			skip = result.offset + len(batch)
+			if len(batch) < batchSize && totalNum > 0 && skip >= totalNum {
+				done = true // Reached the end
+				break
+			}
+			if len(batch) < stride && skip < totalNum {
+				// The rest of this round was requested a full stride further on,
+				// which would leave a gap after a short page; refetch from here
+				break
+			}
+		}
+		if done {
+			break
+		}
+	}
 
-		// Fetch batch
-		batch, err := fetchDiscussArticlesWithSkip(batchSize, skip)
+	if opts.LightQuery && opts.RefetchFullDetails && len(allArticles) > 0 {
+		full, err := fetchFullDetails(ctx, allArticles, opts)
 		if err != nil {
+			return allArticles, err
+		}
+		allArticles = full
+	}
+
+	if chronological {
+		// Guarantee newest-first order however the articles were collected
+		sortArticlesNewestFirst(allArticles)
+	}
+	return allArticles, nil
+}
+
+// pageResult is one page of the listing requested by fetchPages
+type pageResult struct {
+	offset   int
+	articles []Article
+	totalNum int
+	err      error
+}
+
+// fetchPages requests n pages concurrently, at offsets skip, skip+stride and so on,
+// and returns them in offset order. Each page is paced by PageDelay and the shared
+// Limiter as usual; a failed page does not stop the others.
+func fetchPages(ctx context.Context, skip, stride, n int, opts clientOptions) []pageResult {
+	results := make([]pageResult, n)
+	var wg sync.WaitGroup
+	for i := range results {
+		result := &results[i]
+		result.offset = skip + i*stride
+		wg.Go(func() {
+			// Stops promptly between batches when the run is cancelled
+			if result.err = opts.waitBeforePage(ctx, result.offset); result.err != nil {
+				return
+			}
+			fmt.Printf("Fetching batch starting at offset %d...\n", result.offset)
+			result.articles, result.totalNum, result.err = fetchDiscussArticlesWithSkip(ctx, batchSize, result.offset, opts)
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// fetchFullDetails replaces articles found by a light scan with their complete versions.
+// The newest pages are fetched again with the full query and matched by UUID; articles
+// that can no longer be found are kept as they are.
+func fetchFullDetails(ctx context.Context, articles []Article, opts clientOptions) ([]Article, error) {
+	opts.LightQuery = false
+
+	pending := make(map[string]bool, len(articles))
+	for _, article := range articles {
+		pending[article.UUID] = true
+	}
+
+	// Articles posted since the scan push ours further down, so allow one extra page
+	full := make(map[string]Article, len(articles))
+	for skip := 0; len(pending) > 0 && skip < len(articles)+batchSize; {
+		if err := opts.waitBeforePage(ctx, skip); err != nil {
 			return nil, err
 		}
+		fmt.Printf("Fetching full details starting at offset %d...\n", skip)
 
+		batch, _, err := fetchDiscussArticlesWithSkip(ctx, batchSize, skip, opts)
+		if err != nil {
+			return nil, err
+		}
 		if len(batch) == 0 {
-			break // No more articles
+			break
 		}
+		skip += len(batch)
 
-		foundOlderArticle := false
 		for _, article := range batch {
-			articleTime, err := time.Parse(time.RFC3339, article.CreatedAt)
-			if err != nil {
-				continue // Skip if we can't parse the time
+			if pending[article.UUID] {
+				full[article.UUID] = article
+				delete(pending, article.UUID)
 			}
+		}
+	}
 
-			if articleTime.After(cutoffTime) {
-				allArticles = append(allArticles, article)
-			} else {
-				foundOlderArticle = true
-				break
-			}
+	result := make([]Article, len(articles))
+	for i, article := range articles {
+		if detailed, ok := full[article.UUID]; ok {
+			article = detailed
 		}
+		result[i] = article
+	}
+	return result, nil
+}
+
+// fetchDiscussArticlesWithSkip fetches articles with pagination support. It also
+// returns the total number of articles reported by the API. Transient failures are
+// retried up to opts.MaxRetries times with exponential backoff and jitter, or after
+// the wait the server asks for in Retry-After.
+func fetchDiscussArticlesWithSkip(ctx context.Context, count int, skip int, opts clientOptions) ([]Article, int, error) {
+	delay := opts.RetryBaseDelay
+	for attempt := 0; ; attempt++ {
+		articles, totalNum, err := fetchDiscussPage(ctx, count, skip, opts)
 
-		if foundOlderArticle || len(batch) < batchSize {
-			break // Stop if we found older articles or reached the end
+		var fetchErr *FetchError
+		if err == nil || attempt >= opts.MaxRetries || ctx.Err() != nil || !errors.As(err, &fetchErr) || !fetchErr.Transient() {
+			return articles, totalNum, err
 		}
 
-		skip += batchSize
+		wait := fetchErr.RetryAfter
+		if wait <= 0 {
+			wait = delay + rand.N(delay/2+1)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v; retrying in %s (%d of %d)...\n", err, wait.Round(time.Millisecond), attempt+1, opts.MaxRetries)
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, 0, err
+		}
+		delay *= 2
 	}
-
-	return allArticles, nil
 }
 
-// fetchDiscussArticlesWithSkip fetches articles with pagination support
-func fetchDiscussArticlesWithSkip(count int, skip int) ([]Article, error) {
+// fetchDiscussPage makes a single request for one page of articles
+func fetchDiscussPage(ctx context.Context, count int, skip int, opts clientOptions) ([]Article, int, error) {
+	// Both lists are sent as empty arrays rather than null when unset
+	tagSlugs := opts.TagSlugs
+	if tagSlugs == nil {
+		tagSlugs = []string{}
+	}
+	keywords := opts.Keywords
+	if keywords == nil {
+		keywords = []string{}
+	}
+
 	reqBody := map[string]interface{}{
-		"query": discussTopicsQuery,
 		"variables": map[string]interface{}{
-			"orderBy":  "MOST_RECENT",
-			"keywords": []string{},
-			"tagSlugs": []string{},
+			"orderBy":  opts.orderBy(),
+			"keywords": keywords,
+			"tagSlugs": tagSlugs,
 			"skip":     skip,
 			"first":    count,
 		},
 	}
+	usePersisted := opts.PersistedQuery.use()
+	if usePersisted {
+		reqBody["extensions"] = map[string]interface{}{
+			"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": opts.PersistedQuery.Hash},
+		}
+	} else {
+		reqBody["query"] = opts.query()
+	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	client := &http.Client{
-		Timeout: 15 * time.Second,
+		return nil, 0, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", leetcodeGraphQLURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", leetcodeGraphQLURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	opts.setHeaders(req)
 
-	resp, err := client.Do(req)
+	if err := opts.Limiter.wait(ctx); err != nil {
+		return nil, 0, err
+	}
+	resp, err := httpClient(opts.HTTPClient).Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, 0, &FetchError{Skip: skip, First: count, Err: fmt.Errorf("failed to send request: %w", err)}
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, 0, &FetchError{
+			StatusCode: resp.StatusCode,
+			Skip:       skip,
+			First:      count,
+			Body:       bodySnippet(resp.Body),
+			Err: errors.New("leetcode refused the request (403 Forbidden); this usually means anonymous " +
+				"or automated traffic is being blocked. Try setting LEETCODE_SESSION to a logged-in session cookie " +
+				"and/or USER_AGENT to a browser user agent"),
+		}
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, 0, &FetchError{
+			StatusCode: resp.StatusCode,
+			Skip:       skip,
+			First:      count,
+			Body:       bodySnippet(resp.Body),
+			Err:        fmt.Errorf("unexpected status code: %d", resp.StatusCode),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
 	var result ArticlesResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, 0, &FetchError{
+			StatusCode: resp.StatusCode,
+			Skip:       skip,
+			First:      count,
+			Err:        fmt.Errorf("failed to decode response: %w", err),
+		}
+	}
+
+	listing := result.Data.UgcArticleDiscussionArticles
+	if usePersisted && listing == nil && persistedQueryNotFound(result.Errors) {
+		fmt.Fprintf(os.Stderr, "Warning: leetcode does not know PERSISTED_QUERY_HASH; sending the full query instead\n")
+		opts.PersistedQuery.notFound.Store(true)
+		return fetchDiscussPage(ctx, count, skip, opts)
+	}
+	if len(result.Errors) > 0 {
+		if listing == nil {
+			// Without this the page would look empty and end the scan silently
+			return nil, 0, &FetchError{
+				StatusCode: resp.StatusCode,
+				Skip:       skip,
+				First:      count,
+				Err:        fmt.Errorf("leetcode returned GraphQL errors: %s", graphQLErrorMessages(result.Errors)),
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Warning: leetcode returned the page at offset %d with GraphQL errors: %s\n", skip, graphQLErrorMessages(result.Errors))
+	}
+	if listing == nil {
+		return nil, 0, nil
 	}
 
 	var articles []Article
-	for _, edge := range result.Data.UgcArticleDiscussionArticles.Edges {
+	for _, edge := range listing.Edges {
 		articles = append(articles, edge.Node)
 	}
 
 	// Articles are already sorted by NEWEST, no need to sort again
-	return articles, nil
+	return articles, listing.TotalNum, nil
+}
+
+// articleContentQuery fetches the full body of a single article
+const articleContentQuery = `
+		query discussPostContent($topicId: ID!) {
+			ugcArticleDiscussionArticle(topicId: $topicId) {
+				uuid
+				content
+			}
+		}
+	`
+
+// articleContentResponse is the GraphQL response to articleContentQuery
+type articleContentResponse struct {
+	Data struct {
+		UgcArticleDiscussionArticle *struct {
+			UUID    string `json:"uuid"`
+			Content string `json:"content"`
+		} `json:"ugcArticleDiscussionArticle"`
+	} `json:"data"`
+	Errors []GraphQLError `json:"errors"`
+}
+
+// fetchFullContent fills in the Content of articles that have none, one request
+// per article. Requests are paced like pages, by PAGE_DELAY and MAX_RPS, and a
+// rate-limited request is retried once after the wait LeetCode asks for. An
+// article whose content cannot be fetched keeps only its summary.
+func fetchFullContent(ctx context.Context, articles []Article, opts clientOptions) {
+	fetched := 0
+	for i := range articles {
+		if articles[i].Content != "" {
+			continue
+		}
+		if err := opts.waitBeforePage(ctx, fetched); err != nil {
+			return
+		}
+		fetched++
+
+		content, err := fetchArticleContent(ctx, articles[i].TopicId, opts)
+		var fetchErr *FetchError
+		if errors.As(err, &fetchErr) && fetchErr.RateLimited() && fetchErr.RetryAfter > 0 {
+			if err = sleepContext(ctx, fetchErr.RetryAfter); err == nil {
+				content, err = fetchArticleContent(ctx, articles[i].TopicId, opts)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to fetch the content of %q: %v\n", articles[i].Title, err)
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		articles[i].Content = content
+	}
+}
+
+// fetchArticleContent requests the full body of the article with the given topic ID
+func fetchArticleContent(ctx context.Context, topicID int, opts clientOptions) (string, error) {
+	reqBody := map[string]interface{}{
+		"query":     articleContentQuery,
+		"variables": map[string]interface{}{"topicId": strconv.Itoa(topicID)},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", leetcodeGraphQLURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	opts.setHeaders(req)
+
+	if err := opts.Limiter.wait(ctx); err != nil {
+		return "", err
+	}
+	resp, err := httpClient(opts.HTTPClient).Do(req)
+	if err != nil {
+		return "", &FetchError{Err: fmt.Errorf("failed to send request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &FetchError{
+			StatusCode: resp.StatusCode,
+			Body:       bodySnippet(resp.Body),
+			Err:        fmt.Errorf("unexpected status code: %d", resp.StatusCode),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	var result articleContentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", &FetchError{StatusCode: resp.StatusCode, Err: fmt.Errorf("failed to decode response: %w", err)}
+	}
+	if result.Data.UgcArticleDiscussionArticle == nil {
+		if len(result.Errors) > 0 {
+			return "", &FetchError{StatusCode: resp.StatusCode, Err: fmt.Errorf("leetcode returned GraphQL errors: %s", graphQLErrorMessages(result.Errors))}
+		}
+		return "", fmt.Errorf("article %d not found", topicID)
+	}
+	return result.Data.UgcArticleDiscussionArticle.Content, nil
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP
+// date. It returns 0 when the header is absent or unusable.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// bodySnippet reads the start of a response body for use in error messages
+func bodySnippet(r io.Reader) string {
+	const maxSnippet = 300
+	data, _ := io.ReadAll(io.LimitReader(r, maxSnippet+1))
+	if len(data) > maxSnippet {
+		// Drop any character cut in half at the limit
+		return strings.TrimSpace(strings.ToValidUTF8(string(data[:maxSnippet]), "")) + "..."
+	}
+	return strings.TrimSpace(string(data))
 }
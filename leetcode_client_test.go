@@ -0,0 +1,854 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// graphQLRequest is the body of a request to the LeetCode GraphQL endpoint
+type graphQLRequest struct {
+	Query      string                 `json:"query"`
+	Variables  map[string]interface{} `json:"variables"`
+	Extensions map[string]interface{} `json:"extensions"`
+}
+
+// listingJSON encodes a listing response holding articles out of totalNum
+func listingJSON(totalNum int, articles []Article) []byte {
+	var resp ArticlesResponse
+	resp.Data.UgcArticleDiscussionArticles = &struct {
+		TotalNum int `json:"totalNum"`
+		Edges    []struct {
+			Node Article `json:"node"`
+		} `json:"edges"`
+	}{TotalNum: totalNum}
+	for _, article := range articles {
+		resp.Data.UgcArticleDiscussionArticles.Edges = append(resp.Data.UgcArticleDiscussionArticles.Edges, struct {
+			Node Article `json:"node"`
+		}{article})
+	}
+	data, _ := json.Marshal(resp)
+	return data
+}
+
+// newGraphQLServer starts a server answering every GraphQL request with respond
+func newGraphQLServer(t *testing.T, respond func(w http.ResponseWriter, r *http.Request, body graphQLRequest)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body graphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		respond(w, r, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestUserAgent(t *testing.T) {
+	tests := []struct {
+		env  string
+		want string
+	}{
+		{"", defaultUserAgent},
+		{"MyDigest/2.0 (+mailto:me@example.com)", "MyDigest/2.0 (+mailto:me@example.com)"},
+	}
+	for _, tt := range tests {
+		t.Setenv("USER_AGENT", tt.env)
+		var got []string
+		srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, _ graphQLRequest) {
+			got = append(got, r.Header.Get("User-Agent"))
+			w.Write([]byte(`{"data": {"ugcArticleDiscussionArticle": {"uuid": "u1", "content": "body"}}}`))
+		})
+		cfg := testConfig(t)
+		cfg.HTTPClient = serverClient(srv)
+		opts := cfg.ClientOptions()
+
+		// Both the listing and the content request carry it
+		fetchDiscussPage(context.Background(), 1, 0, opts)
+		fetchArticleContent(context.Background(), 1, opts)
+		if len(got) != 2 || got[0] != tt.want || got[1] != tt.want {
+			t.Errorf("USER_AGENT=%q: sent %q, want %q on every request", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want map[string]string
+	}{
+		{
+			name: "defaults",
+			want: map[string]string{
+				"Content-Type": "application/json",
+				"User-Agent":   defaultUserAgent,
+				"Referer":      defaultReferer,
+				"Origin":       defaultOrigin,
+				"Cookie":       "",
+			},
+		},
+		{
+			name: "overridden",
+			env: map[string]string{
+				"USER_AGENT":       "Mozilla/5.0",
+				"LEETCODE_REFERER": "https://leetcode.com/discuss/interview-question/",
+				"LEETCODE_ORIGIN":  "https://leetcode.cn",
+				"LEETCODE_SESSION": "abc123",
+			},
+			want: map[string]string{
+				"Content-Type": "application/json",
+				"User-Agent":   "Mozilla/5.0",
+				"Referer":      "https://leetcode.com/discuss/interview-question/",
+				"Origin":       "https://leetcode.cn",
+				"Cookie":       "LEETCODE_SESSION=abc123",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			var got http.Header
+			srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, _ graphQLRequest) {
+				got = r.Header
+				w.Write(listingJSON(0, nil))
+			})
+			cfg := testConfig(t)
+			cfg.HTTPClient = serverClient(srv)
+
+			if _, _, err := fetchDiscussPage(context.Background(), 1, 0, cfg.ClientOptions()); err != nil {
+				t.Fatal(err)
+			}
+			for header, want := range tt.want {
+				if value := got.Get(header); value != want {
+					t.Errorf("%s = %q, want %q", header, value, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLightQuery(t *testing.T) {
+	cutoff := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	newer := testArticle("new", "2024-05-02T10:00:00Z")
+	older := testArticle("old", "2024-04-30T10:00:00Z")
+
+	var queries []string
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		queries = append(queries, body.Query)
+		full := newer
+		if strings.Contains(body.Query, "reactions") {
+			full.Reactions = []Reaction{{Count: 3, ReactionType: "UPVOTE"}}
+		}
+		w.Write(listingJSON(2, []Article{full, older}))
+	})
+
+	for _, refetch := range []bool{false, true} {
+		queries = nil
+		opts := clientOptions{LightQuery: true, RefetchFullDetails: refetch, HTTPClient: serverClient(srv)}
+		articles, err := fetchArticlesAfterTime(context.Background(), cutoff, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantRequests := 1
+		if refetch {
+			wantRequests = 2
+		}
+		if len(queries) != wantRequests {
+			t.Fatalf("refetch=%v: %d requests, want %d", refetch, len(queries), wantRequests)
+		}
+		for _, field := range []string{"reactions", "tags", "thumbnail"} {
+			if strings.Contains(queries[0], field) {
+				t.Errorf("refetch=%v: light scan query selects %s", refetch, field)
+			}
+		}
+		if refetch {
+			if !strings.Contains(queries[1], "reactions") {
+				t.Error("refetch query does not select reactions")
+			}
+			if len(articles) != 1 || len(articles[0].Reactions) != 1 {
+				t.Errorf("refetched articles = %+v, want the new article with its reactions", articles)
+			}
+		}
+	}
+}
+
+func TestForbiddenGuidance(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<html>Access denied by bot protection</html>", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	_, _, err := fetchDiscussPage(context.Background(), batchSize, 0, clientOptions{HTTPClient: serverClient(srv)})
+	if err == nil {
+		t.Fatal("403 response did not fail")
+	}
+	for _, want := range []string{"403 Forbidden", "LEETCODE_SESSION", "USER_AGENT", "Access denied by bot protection"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+// listingArticles returns n articles one minute apart, newest first, starting at newest
+func listingArticles(n int, newest time.Time) []Article {
+	articles := make([]Article, n)
+	for i := range articles {
+		articles[i] = testArticle(fmt.Sprintf("u%03d", i), newest.Add(-time.Duration(i)*time.Minute).Format(time.RFC3339))
+	}
+	return articles
+}
+
+// pagedServer serves listing as the API would, but never more than pageCap
+// articles per page whatever was asked for
+func pagedServer(t *testing.T, listing []Article, pageCap int) *httptest.Server {
+	return newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		skip := int(body.Variables["skip"].(float64))
+		first := int(body.Variables["first"].(float64))
+		end := min(skip+min(first, pageCap), len(listing))
+		w.Write(listingJSON(len(listing), listing[min(skip, end):end]))
+	})
+}
+
+func TestServerCappedPageSize(t *testing.T) {
+	newest := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	listing := listingArticles(70, newest)
+	srv := pagedServer(t, listing, 30)
+
+	for _, workers := range []int{1, 4} {
+		opts := clientOptions{Workers: workers, HTTPClient: serverClient(srv)}
+		articles, err := fetchArticlesAfterTime(context.Background(), newest.Add(-24*time.Hour), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := uuidsOf(articles), uuidsOf(listing); got != want {
+			t.Errorf("workers=%d: fetched %d articles, want all %d in order", workers, len(articles), len(listing))
+		}
+	}
+}
+
+func TestPageDelay(t *testing.T) {
+	newest := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	listing := listingArticles(250, newest)
+	const delay = 40 * time.Millisecond
+
+	var mu sync.Mutex
+	var requests []time.Time
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		mu.Lock()
+		requests = append(requests, time.Now())
+		mu.Unlock()
+		skip := int(body.Variables["skip"].(float64))
+		end := min(skip+int(body.Variables["first"].(float64)), len(listing))
+		w.Write(listingJSON(len(listing), listing[min(skip, end):end]))
+	})
+
+	opts := clientOptions{Workers: 1, PageDelay: delay, HTTPClient: serverClient(srv)}
+	articles, err := fetchArticlesAfterTime(context.Background(), newest.Add(-24*time.Hour), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != len(listing) {
+		t.Fatalf("fetched %d articles, want %d", len(articles), len(listing))
+	}
+	if len(requests) != 3 {
+		t.Fatalf("made %d requests, want 3", len(requests))
+	}
+	for i := 1; i < len(requests); i++ {
+		if gap := requests[i].Sub(requests[i-1]); gap < delay {
+			t.Errorf("page %d requested %s after the previous one, want at least %s", i+1, gap, delay)
+		}
+	}
+
+	// Cancelling during the delay stops the scan without waiting it out
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	opts.PageDelay = time.Hour
+	start := time.Now()
+	if _, err := fetchArticlesAfterTime(ctx, newest.Add(-24*time.Hour), opts); err == nil {
+		t.Error("cancelled scan did not fail")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled scan took %s", elapsed)
+	}
+}
+
+func TestSparsePage(t *testing.T) {
+	newest := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	listing := listingArticles(250, newest)
+
+	// The middle page comes back empty, as if its articles had been deleted,
+	// while totalNum still counts them
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		skip := int(body.Variables["skip"].(float64))
+		if skip == batchSize {
+			w.Write(listingJSON(len(listing), nil))
+			return
+		}
+		end := min(skip+int(body.Variables["first"].(float64)), len(listing))
+		w.Write(listingJSON(len(listing), listing[min(skip, end):end]))
+	})
+
+	for _, workers := range []int{1, 3} {
+		opts := clientOptions{Workers: workers, HTTPClient: serverClient(srv)}
+		articles, err := fetchArticlesAfterTime(context.Background(), newest.Add(-24*time.Hour), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := append(append([]Article(nil), listing[:batchSize]...), listing[2*batchSize:]...)
+		if got := uuidsOf(articles); got != uuidsOf(want) {
+			t.Errorf("workers=%d: fetched %d articles, want the %d outside the sparse page", workers, len(articles), len(want))
+		}
+	}
+}
+
+func TestSparsePageCappedSize(t *testing.T) {
+	newest := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	listing := listingArticles(70, newest)
+	const pageCap = 30
+
+	// With pages capped at 30, the empty page at 30 is followed by one at 60
+	// even though 30+batchSize is past the total
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		skip := int(body.Variables["skip"].(float64))
+		if skip == pageCap {
+			w.Write(listingJSON(len(listing), nil))
+			return
+		}
+		end := min(skip+pageCap, len(listing))
+		w.Write(listingJSON(len(listing), listing[min(skip, end):end]))
+	})
+
+	for _, workers := range []int{1, 3} {
+		opts := clientOptions{Workers: workers, HTTPClient: serverClient(srv)}
+		articles, err := fetchArticlesAfterTime(context.Background(), newest.Add(-24*time.Hour), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := append(append([]Article(nil), listing[:pageCap]...), listing[2*pageCap:]...)
+		if got := uuidsOf(articles); got != uuidsOf(want) {
+			t.Errorf("workers=%d: fetched %d articles, want the %d outside the sparse page", workers, len(articles), len(want))
+		}
+	}
+}
+
+func TestShortMiddlePage(t *testing.T) {
+	newest := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	listing := listingArticles(350, newest)
+	const short = 60
+
+	// The second page stops early, so the articles after it must be requested
+	// from where it ended rather than from the next full page
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		skip := int(body.Variables["skip"].(float64))
+		size := int(body.Variables["first"].(float64))
+		if skip == batchSize {
+			size = short
+		}
+		end := min(skip+size, len(listing))
+		w.Write(listingJSON(len(listing), listing[min(skip, end):end]))
+	})
+
+	for _, workers := range []int{1, 3} {
+		opts := clientOptions{Workers: workers, HTTPClient: serverClient(srv)}
+		articles, err := fetchArticlesAfterTime(context.Background(), newest.Add(-24*time.Hour), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := uuidsOf(articles), uuidsOf(listing); got != want {
+			t.Errorf("workers=%d: fetched %d articles, want all %d in order", workers, len(articles), len(listing))
+		}
+	}
+}
+
+func TestDisabledFields(t *testing.T) {
+	disabled, err := parseDisabledFields([]string{"Reactions", "tags"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var query string
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		query = body.Query
+		w.Write(listingJSON(0, nil))
+	})
+	opts := clientOptions{DisabledFields: disabled, HTTPClient: serverClient(srv)}
+	if _, _, err := fetchDiscussPage(context.Background(), batchSize, 0, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range []string{"reactions", "reactionType", "tags", "tagType"} {
+		if strings.Contains(query, field) {
+			t.Errorf("query selects disabled field %s", field)
+		}
+	}
+	for _, field := range []string{"uuid", "createdAt", "userName", "thumbnail", "viewCount", "commentCount"} {
+		if !strings.Contains(query, field) {
+			t.Errorf("query does not select %s", field)
+		}
+	}
+
+	if _, err := parseDisabledFields([]string{"uuid"}); err == nil {
+		t.Error("parseDisabledFields accepted a core field")
+	}
+}
+
+func TestTagSlugsFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string // JSON of the tagSlugs variable
+	}{
+		{nil, `[]`},
+		{[]string{"-tags", "dynamic-programming"}, `["dynamic-programming"]`},
+		{[]string{"-tags", "Dynamic-Programming, ,bad slug!,interview-experience,"}, `["dynamic-programming","interview-experience"]`},
+	}
+	for _, tt := range tests {
+		var got string
+		srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+			data, _ := json.Marshal(body.Variables["tagSlugs"])
+			got = string(data)
+			w.Write(listingJSON(0, nil))
+		})
+		cfg, err := loadCommandConfig("test", tt.args, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.HTTPClient = serverClient(srv)
+
+		if _, _, err := fetchDiscussPage(context.Background(), batchSize, 0, cfg.ClientOptions()); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("args %q: sent tagSlugs %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestKeywordsInRequest(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string // JSON of the keywords variable
+	}{
+		{nil, `[]`},
+		{[]string{"-keywords", "google onsite,  sde2"}, `["google","onsite","sde2"]`},
+	}
+	for _, tt := range tests {
+		var got string
+		srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+			data, _ := json.Marshal(body.Variables["keywords"])
+			got = string(data)
+			w.Write(listingJSON(0, nil))
+		})
+		cfg, err := loadCommandConfig("test", tt.args, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.HTTPClient = serverClient(srv)
+
+		if _, _, err := fetchDiscussPage(context.Background(), batchSize, 0, cfg.ClientOptions()); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("args %q: sent keywords %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRankedOrderScansPastOlderArticles(t *testing.T) {
+	cutoff := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	// Ranked by votes, new and old articles alternate all the way down
+	listing := make([]Article, 250)
+	var want []Article
+	for i := range listing {
+		created := cutoff.Add(time.Duration(i+1) * time.Minute)
+		if i%2 == 1 {
+			created = cutoff.Add(-time.Duration(i) * time.Hour)
+		}
+		listing[i] = testArticle(fmt.Sprintf("u%03d", i), created.Format(time.RFC3339))
+		if i%2 == 0 {
+			want = append(want, listing[i])
+		}
+	}
+
+	var orders []string
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		orders = append(orders, body.Variables["orderBy"].(string))
+		skip := int(body.Variables["skip"].(float64))
+		end := min(skip+int(body.Variables["first"].(float64)), len(listing))
+		w.Write(listingJSON(len(listing), listing[min(skip, end):end]))
+	})
+
+	tests := []struct {
+		orderBy      string
+		maxPages     int
+		wantRequests int
+		want         []Article
+	}{
+		{"", 0, 1, want[:1]}, // most recent stops at the first older article
+		{orderMostVotes, 0, 3, want},
+		{orderHottest, 2, 2, want[:batchSize]}, // two pages hold 100 new articles
+	}
+	for _, tt := range tests {
+		orders = nil
+		opts := clientOptions{OrderBy: tt.orderBy, MaxPages: tt.maxPages, Workers: 1, HTTPClient: serverClient(srv)}
+		articles, err := fetchArticlesAfterTime(context.Background(), cutoff, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantOrder := tt.orderBy
+		if wantOrder == "" {
+			wantOrder = orderMostRecent
+		}
+		if len(orders) != tt.wantRequests {
+			t.Errorf("ORDER_BY=%q: %d requests, want %d", tt.orderBy, len(orders), tt.wantRequests)
+		}
+		for _, order := range orders {
+			if order != wantOrder {
+				t.Errorf("ORDER_BY=%q: sent orderBy %s", tt.orderBy, order)
+			}
+		}
+		if got := uuidsOf(articles); got != uuidsOf(tt.want) {
+			t.Errorf("ORDER_BY=%q: fetched %d articles, want %d in ranked order", tt.orderBy, len(articles), len(tt.want))
+		}
+	}
+}
+
+func TestFetchRetries(t *testing.T) {
+	ok := http.StatusOK
+	tests := []struct {
+		name      string
+		statuses  []int // responses in order; the last one repeats
+		wantErr   bool
+		wantCalls int
+	}{
+		{"transient then success", []int{503, 429, 502, ok}, false, 4},
+		{"retries exhausted", []int{500}, true, 4},
+		{"bad request fails fast", []int{400, ok}, true, 1},
+		{"unauthorized fails fast", []int{401, ok}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, _ graphQLRequest) {
+				status := tt.statuses[min(calls, len(tt.statuses)-1)]
+				calls++
+				if status != ok {
+					http.Error(w, "unavailable", status)
+					return
+				}
+				w.Write(listingJSON(1, []Article{testArticle("u1", "2024-05-01T10:00:00Z")}))
+			})
+
+			opts := clientOptions{MaxRetries: 3, RetryBaseDelay: time.Millisecond, HTTPClient: serverClient(srv)}
+			articles, _, err := fetchDiscussArticlesWithSkip(context.Background(), batchSize, 0, opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(articles) != 1 {
+				t.Errorf("got %d articles, want 1", len(articles))
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("server called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"0", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCancelStopsScan(t *testing.T) {
+	newest := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	listing := listingArticles(500, newest)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first page is served normally and then the run is cancelled, as on
+	// SIGINT; any later request hangs until the test ends
+	release := make(chan struct{})
+	var mu sync.Mutex
+	requests := 0
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+		if n > 1 {
+			<-release
+			return
+		}
+		w.Write(listingJSON(len(listing), listing[:batchSize]))
+		cancel()
+	})
+	defer close(release)
+
+	start := time.Now()
+	_, err := fetchArticlesAfterTime(ctx, newest.Add(-24*time.Hour), clientOptions{Workers: 1, HTTPClient: serverClient(srv)})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled scan took %s", elapsed)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests > 2 {
+		t.Errorf("made %d requests after the cancel", requests-1)
+	}
+}
+
+func TestCancelStopsEmail(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := SendGridSender{APIKey: "key", Client: serverClient(srv)}.Send(ctx, emailMessage{Subject: "Digest"}, []string{"a@example.com"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the deadline error", err)
+	}
+}
+
+func TestFetchLatestStopsAtCount(t *testing.T) {
+	newest := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	listing := listingArticles(5*batchSize, newest)
+	var mu sync.Mutex
+	requests := 0
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		skip := int(body.Variables["skip"].(float64))
+		w.Write(listingJSON(len(listing), listing[skip:min(skip+batchSize, len(listing))]))
+	})
+
+	for _, n := range []int{5, batchSize + 50} {
+		mu.Lock()
+		requests = 0
+		mu.Unlock()
+		articles, err := fetchLatestArticles(context.Background(), n, clientOptions{Workers: 1, HTTPClient: serverClient(srv)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := uuidsOf(articles), uuidsOf(listing[:n]); got != want {
+			t.Errorf("n=%d: fetched %d articles, want the newest %d", n, len(articles), n)
+		}
+		mu.Lock()
+		if want := (n + batchSize - 1) / batchSize; requests != want {
+			t.Errorf("n=%d: made %d requests, want %d", n, requests, want)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestFetchFullContent(t *testing.T) {
+	var mu sync.Mutex
+	requested := make(map[string]int)
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		if !strings.Contains(body.Query, "ugcArticleDiscussionArticle(") {
+			t.Errorf("not a content query: %s", body.Query)
+		}
+		topicID, _ := body.Variables["topicId"].(string)
+		mu.Lock()
+		requested[topicID]++
+		n := requested[topicID]
+		mu.Unlock()
+
+		switch {
+		case topicID == "2" && n == 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case topicID == "3":
+			w.Write([]byte(`{"data": {"ugcArticleDiscussionArticle": null}}`))
+		default:
+			fmt.Fprintf(w, `{"data": {"ugcArticleDiscussionArticle": {"uuid": "u%s", "content": "Body of %s\n"}}}`, topicID, topicID)
+		}
+	})
+
+	article := func(uuid string, topicID int, content string) Article {
+		a := testArticle(uuid, "2024-05-01T10:00:00Z")
+		a.TopicId, a.Content = topicID, content
+		return a
+	}
+	articles := []Article{article("u1", 1, ""), article("u2", 2, ""), article("u3", 3, ""), article("u4", 4, "Already here")}
+	fetchFullContent(context.Background(), articles, clientOptions{HTTPClient: serverClient(srv)})
+
+	var contents []string
+	for _, a := range articles {
+		contents = append(contents, a.Content)
+	}
+	if got, want := strings.Join(contents, "|"), "Body of 1\n|Body of 2\n||Already here"; got != want {
+		t.Errorf("contents %q, want %q", got, want)
+	}
+	if got := fmt.Sprint(requested); got != "map[1:1 2:2 3:1]" {
+		t.Errorf("requests per topic %s, want one each, a retry for the rate-limited one and none for existing content", got)
+	}
+
+	filename := filepath.Join(t.TempDir(), "digest.txt")
+	if err := writeArticlesToFile(articles[:1], filename, testConfig(t)); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, filename); !strings.Contains(content, "\n--- Content ---\nBody of 1\n") {
+		t.Errorf("file lacks the content section:\n%s", content)
+	}
+}
+
+func TestConcurrentPagesOutOfOrder(t *testing.T) {
+	newest := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	listing := listingArticles(10*batchSize, newest)
+	// Articles without a UUID are distinct articles all the same
+	for _, i := range []int{10, 250, 420} {
+		listing[i].UUID = ""
+	}
+	const cutoffIndex = 650
+	cutoff, _ := parseArticleTime(listing[cutoffIndex].CreatedAt)
+
+	// Later pages answer sooner, so every round completes in reverse order
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		skip := int(body.Variables["skip"].(float64))
+		time.Sleep(time.Duration(len(listing)-skip) * 20 * time.Millisecond / batchSize)
+		w.Write(listingJSON(len(listing), listing[skip:min(skip+batchSize, len(listing))]))
+	})
+
+	for _, workers := range []int{1, 4} {
+		articles, err := fetchArticlesAfterTime(context.Background(), cutoff, clientOptions{Workers: workers, HTTPClient: serverClient(srv)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(articles) != cutoffIndex {
+			t.Errorf("workers=%d: fetched %d articles, want the %d after the cutoff", workers, len(articles), cutoffIndex)
+		}
+		if got, want := uuidsOf(articles), uuidsOf(listing[:cutoffIndex]); got != want {
+			t.Errorf("workers=%d: articles not merged newest first", workers)
+		}
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	errorPayload, err := os.ReadFile(testdataPath(t, "discuss_error.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Errors alongside data, as for a field LeetCode failed to resolve
+	var partial map[string]any
+	json.Unmarshal(listingJSON(1, []Article{testArticle("u1", "2024-05-01T10:00:00Z")}), &partial)
+	partial["errors"] = []map[string]string{{"message": "reactions temporarily unavailable"}}
+	partialPayload, _ := json.Marshal(partial)
+
+	tests := []struct {
+		name      string
+		payload   []byte
+		wantErr   string
+		wantUUIDs string
+	}{
+		{"errors without data", errorPayload, `leetcode returned GraphQL errors: Cannot query field "reactions" on type "ArticleNode".`, ""},
+		{"errors with data", partialPayload, "", "u1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, _ graphQLRequest) {
+				w.Write(tt.payload)
+			})
+
+			articles, _, err := fetchDiscussPage(context.Background(), batchSize, 0, clientOptions{HTTPClient: serverClient(srv)})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := uuidsOf(articles); got != tt.wantUUIDs {
+					t.Errorf("articles = %s, want %s", got, tt.wantUUIDs)
+				}
+				return
+			}
+			var fetchErr *FetchError
+			if !errors.As(err, &fetchErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want a FetchError with %q", err, tt.wantErr)
+			}
+			if fetchErr.Transient() {
+				t.Error("a GraphQL error is reported as transient and would be retried")
+			}
+		})
+	}
+}
+
+func TestPersistedQueryFallback(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	tests := []struct {
+		name         string
+		known        bool   // the server has the query for hash
+		notFound     string // error payload when it does not
+		wantRequests string // per request, "hash" or "query"
+	}{
+		{"known hash", true, "", "hash,hash"},
+		{"not found message", false, `{"errors":[{"message":"PersistedQueryNotFound"}],"data":null}`, "hash,query,query"},
+		{"not found code", false, `{"errors":[{"message":"unknown query","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`, "hash,query,query"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+				if body.Query != "" {
+					requests = append(requests, "query")
+				} else {
+					requests = append(requests, "hash")
+					persisted, _ := body.Extensions["persistedQuery"].(map[string]interface{})
+					if persisted["sha256Hash"] != hash {
+						t.Errorf("extensions = %v, want the sha256Hash %s", body.Extensions, hash)
+					}
+					if !tt.known {
+						w.Write([]byte(tt.notFound))
+						return
+					}
+				}
+				w.Write(listingJSON(1, []Article{testArticle("u1", "2024-05-01T10:00:00Z")}))
+			})
+
+			// The fallback sticks: the second page goes straight to the full query
+			opts := clientOptions{HTTPClient: serverClient(srv), PersistedQuery: &persistedQuery{Hash: hash}}
+			for page := range 2 {
+				articles, _, err := fetchDiscussPage(context.Background(), batchSize, page*batchSize, opts)
+				if err != nil || len(articles) != 1 {
+					t.Fatalf("page %d: got %d articles, %v", page+1, len(articles), err)
+				}
+			}
+			if got := strings.Join(requests, ","); got != tt.wantRequests {
+				t.Errorf("requests = %s, want %s", got, tt.wantRequests)
+			}
+		})
+	}
+}
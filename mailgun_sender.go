@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+const mailgunAPIURL = "https://api.mailgun.net/v3/%s/messages"
+
+// MailgunSender sends email through the Mailgun messages API
+type MailgunSender struct {
+	Domain string
+	APIKey string
+	Client Doer // nil means defaultHTTPClient
+}
+
+func (s MailgunSender) Send(ctx context.Context, msg emailMessage, to []string) error {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	from := mail.Address{Name: msg.FromName, Address: msg.FromEmail}
+	fields := [][2]string{
+		{"from", from.String()},
+		{"subject", msg.Subject},
+		{"text", msg.Text},
+		{"html", msg.HTML},
+	}
+	for _, email := range to {
+		fields = append(fields, [2]string{"to", email})
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return fmt.Errorf("failed to build mailgun request: %w", err)
+		}
+	}
+
+	for _, a := range msg.Attachments {
+		part, err := form.CreateFormFile("attachment", a.Filename)
+		if err != nil {
+			return fmt.Errorf("failed to build mailgun request: %w", err)
+		}
+		part.Write(a.Content)
+	}
+	if err := form.Close(); err != nil {
+		return fmt.Errorf("failed to build mailgun request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf(mailgunAPIURL, url.PathEscape(s.Domain))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth("api", s.APIKey)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := httpClient(s.Client).Do(req)
+	if err != nil {
+		return &EmailError{Provider: providerMailgun, Err: fmt.Errorf("failed to send request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return newMailgunError(resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// newMailgunError builds an EmailError from a Mailgun error response, which looks
+// like {"message": "..."}
+func newMailgunError(status int, body []byte) *EmailError {
+	var parsed struct {
+		Message string `json:"message"`
+	}
+
+	emailErr := &EmailError{Provider: providerMailgun, StatusCode: status}
+	if json.Unmarshal(body, &parsed) == nil && parsed.Message != "" {
+		emailErr.Messages = []string{parsed.Message}
+	} else if len(body) > 0 {
+		emailErr.Messages = []string{strings.TrimSpace(string(body))}
+	}
+	return emailErr
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMailgunSend(t *testing.T) {
+	msg := emailMessage{
+		FromEmail: "bot@example.com", FromName: "Digest Bot", Subject: "Digest",
+		Text: "plain", HTML: "<p>html</p>",
+		Attachments: []emailAttachment{{Filename: "articles.json", Content: []byte("[]")}},
+	}
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "accepted", status: http.StatusOK, body: `{"id": "<1@mg.example.com>", "message": "Queued. Thank you."}`},
+		{name: "JSON error", status: http.StatusUnauthorized, body: `{"message": "Invalid private key"}`, wantErr: "mailgun API returned status 401: Invalid private key"},
+		{name: "plain error", status: http.StatusBadRequest, body: "'to' parameter is missing\n", wantErr: "mailgun API returned status 400: 'to' parameter is missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseMultipartForm(1 << 20); err != nil {
+					t.Errorf("request is not multipart: %v", err)
+				}
+				got = r
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			sender := MailgunSender{Domain: "mg.example.com", APIKey: "key-123", Client: serverClient(srv)}
+			err := sender.Send(context.Background(), msg, []string{"a@example.com", "b@example.com"})
+			if tt.wantErr != "" {
+				var emailErr *EmailError
+				if !errors.As(err, &emailErr) || err.Error() != tt.wantErr {
+					t.Fatalf("Send() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Method != http.MethodPost || got.URL.Path != "/v3/mg.example.com/messages" {
+				t.Errorf("request %s %s", got.Method, got.URL.Path)
+			}
+			if user, pass, ok := got.BasicAuth(); !ok || user != "api" || pass != "key-123" {
+				t.Errorf("basic auth %q:%q, want api:key-123", user, pass)
+			}
+			form := got.MultipartForm.Value
+			for field, want := range map[string]string{
+				"from":    `"Digest Bot" <bot@example.com>`,
+				"subject": "Digest",
+				"text":    "plain",
+				"html":    "<p>html</p>",
+				"to":      "a@example.com,b@example.com",
+			} {
+				if value := strings.Join(form[field], ","); value != want {
+					t.Errorf("%s = %q, want %q", field, value, want)
+				}
+			}
+			if files := got.MultipartForm.File["attachment"]; len(files) != 1 || files[0].Filename != "articles.json" {
+				t.Errorf("attachments %v, want articles.json", files)
+			}
+		})
+	}
+}
+
+func TestMailgunProvider(t *testing.T) {
+	t.Setenv("MAILGUN_DOMAIN", "mg.example.com")
+	t.Setenv("MAILGUN_API_KEY", "key-123")
+	cfg, err := loadCommandConfig("test", []string{"-provider", "mailgun"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := MailgunSender{Domain: "mg.example.com", APIKey: "key-123"}
+	if got := cfg.Sender(); fmt.Sprintf("%#v", got) != fmt.Sprintf("%#v", want) {
+		t.Errorf("Sender() = %#v, want %#v", got, want)
+	}
+}
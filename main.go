@@ -1,126 +1,71 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
-	"time"
 )
 
-func main() {
-	ist := time.FixedZone("IST", 5*3600+30*60)
-
-	// Read configuration from environment variables
-	sendGridAPIKey := strings.TrimSpace(os.Getenv("SENDGRID_API_KEY"))
-	fromEmail := strings.TrimSpace(os.Getenv("FROM_EMAIL"))
-	fromName := strings.TrimSpace(os.Getenv("FROM_NAME"))
-	toEmailsStr := os.Getenv("TO_EMAILS")                          // Comma-separated list
-	enableFileOutput := os.Getenv("ENABLE_FILE_OUTPUT") != "false" // Default to true
-
-	// Parse recipient emails
-	var toEmails []string
-	if toEmailsStr != "" {
-		toEmails = strings.Split(toEmailsStr, ",")
-		for i := range toEmails {
-			toEmails[i] = strings.TrimSpace(toEmails[i])
-		}
-	}
-
-	// Validate configuration
-	enableEmail := sendGridAPIKey != "" && fromEmail != "" && len(toEmails) > 0
-	if !enableEmail && !enableFileOutput {
-		fmt.Fprintf(os.Stderr, "Error: Either email or file output must be enabled\n")
-		os.Exit(1)
-	}
-
-	// Read last processed timestamp from file
-	lastProcessed, err := readLastProcessedTimestamp()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading last processed timestamp: %v\n", err)
-		os.Exit(1)
-	}
+// appVersion is the released version of the tool
+const appVersion = "1.0.0"
 
-	var cutoffTime time.Time
-	if lastProcessed.IsZero() {
-		// First run - fetch articles from last 24 hours
-		cutoffTime = time.Now().Add(-24 * time.Hour)
-		fmt.Println("First run - fetching articles from last 24 hours...")
-	} else {
-		cutoffTime = lastProcessed
-		fmt.Printf("Last processed: %s\n", lastProcessed.In(ist).Format("2006-01-02 03:04 PM MST"))
-	}
+// command is a subcommand selected by the first command-line argument
+type command struct {
+	description string
+	run         func(ctx context.Context, args []string) int
+}
 
-	fmt.Printf("Fetching articles published after %s...\n", cutoffTime.In(ist).Format("2006-01-02 03:04 PM MST"))
+// commands lists the available subcommands
+var commands = map[string]command{
+	"fetch":   {"fetch new articles and write them to files without sending email", cmdFetch},
+	"send":    {"deliver a previously saved JSON digest by email and the configured notifiers", cmdSend},
+	"check":   {"run configuration and connectivity diagnostics", cmdCheck},
+	"version": {"print the version", cmdVersion},
+}
 
-	// Fetch all articles after cutoff time using pagination
-	articles, err := fetchArticlesAfterTime(cutoffTime)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error fetching discuss articles: %v\n", err)
-		os.Exit(1)
-	}
+func main() {
+	// Ctrl-C cancels the context, so in-flight requests stop and the run ends cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
 
-	if len(articles) == 0 {
-		fmt.Println("No new articles found.")
-		return
+// run dispatches to the named subcommand. Without one it fetches and delivers
+// the digest, as the tool always has.
+func run(ctx context.Context, args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return cmdDigest(ctx, args)
 	}
 
-	fmt.Printf("Found %d articles published after cutoff time.\n", len(articles))
-
-	// Print article summary
-	for i, article := range articles {
-		creationTime := formatStringTimestamp(article.CreatedAt)
-		fmt.Printf("\n%d. %s\n", i+1, article.Title)
-		fmt.Printf("   Created: %s\n", creationTime)
-		fmt.Printf("   URL: https://leetcode.com/discuss/post/%d/%s/\n", article.TopicId, article.Slug)
+	if args[0] == "help" {
+		printUsage()
+		return 0
 	}
 
-	// Send email if configured
-	if enableEmail {
-		fmt.Println("\nSending email...")
-		subject := fmt.Sprintf("📚 LeetCode Daily Digest - %d New Articles", len(articles))
-		htmlContent := generateHTMLEmail(articles, ist)
-
-		if fromName == "" {
-			fromName = "LeetCode Articles Bot"
-		}
-
-		err = sendEmailViaSendGrid(sendGridAPIKey, fromEmail, fromName, toEmails, subject, htmlContent)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error sending email: %v\n", err)
-			// Don't exit, continue with file output if enabled
-		} else {
-			fmt.Printf("✓ Successfully sent email to: %s\n", strings.Join(toEmails, ", "))
-		}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", args[0])
+		printUsage()
+		return 2
 	}
+	return cmd.run(ctx, args[1:])
+}
 
-	// Write to file if enabled
-	if enableFileOutput {
-		// Ensure fetched_articles directory exists
-		if err := os.MkdirAll("fetched_articles", 0755); err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating fetched_articles directory: %v\n", err)
-			os.Exit(1)
-		}
-
-		filename := fmt.Sprintf("fetched_articles/leetcode_articles_%s.txt", time.Now().In(ist).Format("2006-01-02_15-04-05"))
-		err = writeArticlesToFile(articles, filename)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing articles to file: %v\n", err)
-			os.Exit(1)
-		}
+// printUsage lists the subcommands
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [command] [flags]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Without a command, new articles are fetched, emailed and saved.\n\nCommands:\n")
 
-		fmt.Printf("✓ Successfully saved %d articles to %s\n", len(articles), filename)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
-
-	// Update last processed timestamp with the most recent article
-	if len(articles) > 0 {
-		// Articles are sorted newest first, so the first one is the most recent
-		newestTime, err := time.Parse(time.RFC3339, articles[0].CreatedAt)
-		if err == nil {
-			if err := writeLastProcessedTimestamp(newestTime); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to update last processed timestamp: %v\n", err)
-			} else {
-				fmt.Printf("Updated last processed timestamp to: %s\n", newestTime.In(ist).Format("2006-01-02 03:04 PM MST"))
-			}
-		}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", name, commands[name].description)
 	}
 }
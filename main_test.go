@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testConfig returns the configuration loaded from the environment, which in
+// tests is the defaults plus whatever the test set with t.Setenv
+func testConfig(t *testing.T) Config {
+	t.Helper()
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	return cfg
+}
+
+// inTempDir runs the rest of the test from a new empty directory, since output
+// files are written to ./fetched_articles
+func inTempDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	return dir
+}
+
+// testdataPath returns the absolute path of a file in testdata, so it stays
+// valid after inTempDir
+func testdataPath(t *testing.T, name string) string {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// loadFixture decodes a GraphQL response from testdata
+func loadFixture(t *testing.T, name string) ArticlesResponse {
+	t.Helper()
+	data, err := os.ReadFile(testdataPath(t, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp ArticlesResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return resp
+}
+
+// testArticle returns a minimal article that the writers and filters accept
+func testArticle(uuid, createdAt string) Article {
+	return Article{
+		UUID:        uuid,
+		TopicId:     1,
+		Title:       "Article " + uuid,
+		Slug:        "article-" + uuid,
+		Author:      Author{UserName: "author-" + uuid},
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+		ArticleType: "DISCUSSION",
+	}
+}
+
+// readFile returns the content of a file the test expects to exist
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// writeFile creates a file with content
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// doerFunc adapts a function to the Doer interface
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+// serverClient returns a Doer that sends every request to srv, whichever host
+// it was addressed to, so code calling the real APIs can be pointed at a test server
+func serverClient(srv *httptest.Server) Doer {
+	target, _ := url.Parse(srv.URL)
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+		return srv.Client().Do(req)
+	})
+}
+
+// routeDefaultClient starts a server with handler and makes defaultHTTPClient
+// send every request there for the rest of the test, for code that builds its
+// configuration itself
+func routeDefaultClient(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	saved := defaultHTTPClient
+	defaultHTTPClient = serverClient(srv)
+	t.Cleanup(func() { defaultHTTPClient = saved })
+	return srv
+}
+
+func TestRunDispatch(t *testing.T) {
+	// Swap the subcommands for stubs recording what they were called with
+	var called []string
+	saved := commands
+	t.Cleanup(func() { commands = saved })
+	commands = make(map[string]command)
+	for name := range saved {
+		commands[name] = command{saved[name].description, func(_ context.Context, args []string) int {
+			called = append(called, name+" "+strings.Join(args, " "))
+			return 0
+		}}
+	}
+
+	tests := []struct {
+		args     []string
+		wantCode int
+		want     string // the stub call, empty when no subcommand runs
+	}{
+		{[]string{"fetch", "-force"}, 0, "fetch -force"},
+		{[]string{"send", "-input", "a.json"}, 0, "send -input a.json"},
+		{[]string{"check"}, 0, "check "},
+		{[]string{"version"}, 0, "version "},
+		{[]string{"help"}, 0, ""},
+		{[]string{"bogus"}, 2, ""},
+	}
+	for _, tt := range tests {
+		called = nil
+		if code := run(context.Background(), tt.args); code != tt.wantCode {
+			t.Errorf("run(%q) = %d, want %d", tt.args, code, tt.wantCode)
+		}
+		if got := strings.Join(called, "; "); got != tt.want {
+			t.Errorf("run(%q) called %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRunDefaultsToDigest(t *testing.T) {
+	inTempDir(t)
+	// Without a subcommand the flags belong to the digest; -explain makes no requests
+	if code := run(context.Background(), []string{"-explain"}); code != 0 {
+		t.Errorf("run(-explain) = %d, want 0", code)
+	}
+	if code := run(context.Background(), []string{"-no-such-flag"}); code != 2 {
+		t.Errorf("run(-no-such-flag) = %d, want the usage exit code 2", code)
+	}
+}
+
+// captureStdout returns what fn prints to standard output
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	defer func() { os.Stdout = saved }()
+	fn()
+	w.Close()
+	return <-done
+}
+
+// fakeSender is an EmailSender that records what it is asked to send, failing
+// with err when it is set
+type fakeSender struct {
+	err  error
+	sent []sentEmail
+}
+
+type sentEmail struct {
+	msg emailMessage
+	to  []string
+}
+
+func (s *fakeSender) Send(_ context.Context, msg emailMessage, to []string) error {
+	s.sent = append(s.sent, sentEmail{msg, to})
+	return s.err
+}
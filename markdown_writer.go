@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// markdownUUIDPrefix starts the hidden comment that records each article's UUID,
+// so daily files can skip articles they already contain
+const markdownUUIDPrefix = "<!-- uuid: "
+
+// writeArticlesToMarkdown writes the articles as a Markdown document with one
+// "##" section per article
+func writeArticlesToMarkdown(articles []Article, filename string, cfg Config) error {
+	return writeOutput(filename, cfg.AtomicWrite, func(w io.Writer) {
+		fmt.Fprintf(w, "# %s - Latest %d Articles%s\n\n", fileTitle(cfg.RunLabel), len(articles), showingNote(len(articles), cfg))
+		fmt.Fprintf(w, "_Fetched on: %s_\n\n", time.Now().In(cfg.Location).Format(cfg.DateFormat.DateTime))
+
+		writeMarkdownSections(w, articles, cfg)
+	})
+}
+
+// appendArticlesToMarkdown adds articles to an existing Markdown digest under a run
+// heading, skipping any whose UUID the file already records. A missing file is
+// written in full. It returns the number of articles added.
+func appendArticlesToMarkdown(articles []Article, filename string, cfg Config) (added int, err error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return len(articles), writeArticlesToMarkdown(articles, filename, cfg)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read existing file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(line, markdownUUIDPrefix); ok {
+			seen[strings.TrimSuffix(rest, " -->")] = true
+		}
+	}
+
+	fresh := excludeSeenArticles(articles, seen)
+	if len(fresh) == 0 {
+		return 0, nil
+	}
+
+	writeRun := func(w io.Writer) {
+		fmt.Fprintf(w, "---\n\n# Run on: %s - %d New Articles\n\n", time.Now().In(cfg.Location).Format(cfg.DateFormat.DateTime), len(fresh))
+		writeMarkdownSections(w, fresh, cfg)
+	}
+
+	if cfg.AtomicWrite {
+		err = writeOutput(filename, true, func(w io.Writer) {
+			w.Write(data)
+			writeRun(w)
+		})
+		return len(fresh), err
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer closeWithError(file, &err)
+
+	ew := &errWriter{w: file}
+	writeRun(ew)
+	return len(fresh), ew.err
+}
+
+// writeMarkdownSections writes one section per article: a linked title, an
+// italic meta line, the summary as a blockquote and the tags as code spans
+func writeMarkdownSections(w io.Writer, articles []Article, cfg Config) {
+	for _, article := range articles {
+		fmt.Fprintf(w, "## [%s](%s)\n", escapeMarkdown(article.Title), articleURL(article))
+		fmt.Fprintf(w, "%s%s -->\n\n", markdownUUIDPrefix, article.UUID)
+
+		fmt.Fprintf(w, "_By %s • %s • %s_\n\n",
+			escapeMarkdown(authorName(article, cfg)),
+			formatStringTimestamp(article.CreatedAt, cfg.DateFormat.DateTime, cfg.Location),
+			escapeMarkdown(article.ArticleType))
+
+		if summary := plainSummary(article.Summary, cfg.HTMLStripMode); summary != "" {
+			for _, line := range strings.Split(strings.TrimSpace(summary), "\n") {
+				fmt.Fprintf(w, "> %s\n", escapeMarkdown(line))
+			}
+			fmt.Fprintf(w, "\n")
+		}
+
+		if len(article.Tags) > 0 {
+			tags, hidden := visibleTags(article.Tags, cfg.MaxTagsShown)
+			spans := make([]string, 0, len(tags)+1)
+			for _, tag := range tags {
+				// A backtick would end the code span early
+				spans = append(spans, "`"+strings.ReplaceAll(tag.Name, "`", "'")+"`")
+			}
+			if hidden > 0 {
+				spans = append(spans, fmt.Sprintf("+%d more", hidden))
+			}
+			fmt.Fprintf(w, "%s\n\n", strings.Join(spans, " "))
+		}
+	}
+}
+
+var (
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `(`, `\(`, `)`, `\)`,
+		`#`, `\#`, `+`, `\+`, `!`, `\!`, `|`, `\|`, `<`, `\<`, `>`, `\>`, `~`, `\~`,
+	)
+	// markdownListStart matches line starts that would turn text into a list item
+	markdownListStart = regexp.MustCompile(`^(\s*)(-|\d+\.)`)
+)
+
+// escapeMarkdown escapes characters that Markdown would treat as formatting, so
+// user content always renders as plain text
+func escapeMarkdown(s string) string {
+	s = markdownEscaper.Replace(s)
+	return markdownListStart.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasSuffix(m, "-") {
+			return strings.TrimSuffix(m, "-") + `\-`
+		}
+		return strings.TrimSuffix(m, ".") + `\.`
+	})
+}
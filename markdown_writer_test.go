@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain title", "plain title"},
+		{"a*b*c", `a\*b\*c`},
+		{"[link](url)", `\[link\]\(url\)`},
+		{"snake_case `code`", "snake\\_case \\`code\\`"},
+		{"- not a list", `\- not a list`},
+		{"1. not a list", `1\. not a list`},
+		{"C# <html>", `C\# \<html\>`},
+	}
+	for _, tt := range tests {
+		if got := escapeMarkdown(tt.in); got != tt.want {
+			t.Errorf("escapeMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownSections(t *testing.T) {
+	cfg, err := loadCommandConfig("test", []string{"-format", "markdown"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OutputFormat != formatMarkdown {
+		t.Fatalf("-format markdown selected %q", cfg.OutputFormat)
+	}
+
+	article := testArticle("u1", "2024-05-01T10:00:00Z")
+	article.Title = "Top *10* [tips]"
+	article.Summary = "First line\n- second line"
+	article.Tags = []Tag{{Name: "Binary Search"}, {Name: "Array"}}
+	filename := filepath.Join(t.TempDir(), "digest.md")
+	if err := writeArticlesToMarkdown([]Article{article}, filename, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	content := readFile(t, filename)
+	for _, want := range []string{
+		"# LeetCode Discuss - Latest 1 Articles\n",
+		`## [Top \*10\* \[tips\]](` + articleURL(article) + ")\n",
+		markdownUUIDPrefix + "u1 -->\n",
+		"_By author-u1 • 2024-05-01 15:30:00 IST • DISCUSSION_\n",
+		"> First line\n> \\- second line\n",
+		"`Binary Search` `Array`\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Markdown does not contain %q:\n%s", want, content)
+		}
+	}
+}
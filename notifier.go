@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Notifier posts the digest to a chat channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, articles []Article, cfg Config) error
+}
+
+// articleFormat is how a channel renders each article. Every channel has its own,
+// so a chat message can stay terse while the email is detailed.
+type articleFormat struct {
+	Layout     string   // layoutFull or layoutCompact (title and meta line only)
+	SummaryLen int      // runes of the summary shown before it is truncated
+	Sections   []string // blocks of the full layout in order, see parseCardLayout
+}
+
+// defaultSummaryLen is the summary length unless <CHANNEL>_SUMMARY_LENGTH is set
+const defaultSummaryLen = 250
+
+// loadArticleFormat reads a channel's <prefix>_LAYOUT, <prefix>_SUMMARY_LENGTH and
+// <prefix>_SECTIONS settings, using def for those that are unset
+func loadArticleFormat(prefix string, def articleFormat) (articleFormat, error) {
+	format := def
+	format.Layout = strings.ToLower(envString(prefix+"_LAYOUT", def.Layout))
+	if format.Layout != layoutFull && format.Layout != layoutCompact {
+		return articleFormat{}, fmt.Errorf("invalid %s_LAYOUT %q: must be %s or %s", prefix, format.Layout, layoutFull, layoutCompact)
+	}
+
+	var err error
+	if format.SummaryLen, err = envInt(prefix+"_SUMMARY_LENGTH", def.SummaryLen); err != nil {
+		return articleFormat{}, err
+	}
+	if format.SummaryLen < 1 {
+		return articleFormat{}, fmt.Errorf("%s_SUMMARY_LENGTH must be at least 1; leave summary out of %s_SECTIONS to hide it", prefix, prefix)
+	}
+
+	if sections := envList(prefix + "_SECTIONS"); len(sections) > 0 {
+		if format.Sections, err = parseCardLayout(sections); err != nil {
+			return articleFormat{}, fmt.Errorf("invalid %s_SECTIONS: %w", prefix, err)
+		}
+	}
+	return format, nil
+}
+
+// SlackNotifier posts the digest to a Slack incoming webhook
+type SlackNotifier struct {
+	WebhookURL string
+	Format     articleFormat
+	Client     Doer // nil means defaultHTTPClient
+}
+
+func (n SlackNotifier) Name() string { return "Slack" }
+
+func (n SlackNotifier) Notify(ctx context.Context, articles []Article, cfg Config) error {
+	return postToSlack(ctx, httpClient(n.Client), n.WebhookURL, articles, n.Format, cfg)
+}
+
+// DiscordNotifier posts the digest to a Discord webhook
+type DiscordNotifier struct {
+	WebhookURL string
+	Format     articleFormat
+	Client     Doer // nil means defaultHTTPClient
+}
+
+func (n DiscordNotifier) Name() string { return "Discord" }
+
+func (n DiscordNotifier) Notify(ctx context.Context, articles []Article, cfg Config) error {
+	return postToDiscord(ctx, httpClient(n.Client), n.WebhookURL, articles, n.Format, cfg)
+}
+
+// chatArticleLines renders the sections of a chat message's article as plain
+// text, one line each. The compact layout has none.
+func chatArticleLines(article Article, format articleFormat, cfg Config) []string {
+	if format.Layout == layoutCompact {
+		return nil
+	}
+
+	var lines []string
+	for _, section := range format.Sections {
+		switch section {
+		case "summary":
+			if summary := plainSummary(article.Summary, cfg.HTMLStripMode); summary != "" {
+				lines = append(lines, truncateText(summary, format.SummaryLen))
+			}
+		case "tags":
+			if len(article.Tags) == 0 {
+				continue
+			}
+			tags, hidden := visibleTags(article.Tags, cfg.MaxTagsShown)
+			names := make([]string, 0, len(tags)+1)
+			for _, tag := range tags {
+				names = append(names, tag.Name)
+			}
+			if hidden > 0 {
+				names = append(names, fmt.Sprintf("+%d more", hidden))
+			}
+			lines = append(lines, "Tags: "+strings.Join(names, ", "))
+		case "reactions":
+			if len(article.Reactions) == 0 {
+				continue
+			}
+			var reactions []string
+			for _, reaction := range mergeReactions(article.Reactions) {
+				reactions = append(reactions, formatReaction(cfg.ReactionFormat, reaction))
+			}
+			lines = append(lines, "Reactions: "+strings.Join(reactions, ", "))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNotifierFormats(t *testing.T) {
+	t.Setenv("SLACK_SUMMARY_LENGTH", "10")
+	t.Setenv("DISCORD_SUMMARY_LENGTH", "30")
+	t.Setenv("DISCORD_SECTIONS", "tags,summary")
+	cfg := testConfig(t)
+	cfg.PostToSlack, cfg.SlackWebhookURL = true, "https://hooks.slack.com/services/T/B/X"
+	cfg.PostToDiscord, cfg.DiscordWebhookURL = true, "https://discord.com/api/webhooks/1/x"
+
+	summary := "Monotonic stacks find the next greater element in linear time."
+	article := testArticle("u1", "2024-05-01T10:00:00Z")
+	article.Summary = summary
+	article.Tags = []Tag{{Name: "Stack", Slug: "stack"}}
+
+	posted := make(map[string]string)
+	cfg.HTTPClient = doerFunc(func(r *http.Request) (*http.Response, error) {
+		switch {
+		case strings.Contains(r.URL.Host, "slack"):
+			var msg slackMessage
+			json.NewDecoder(r.Body).Decode(&msg)
+			posted["Slack"] = msg.Blocks[1].Text.Text
+		case strings.Contains(r.URL.Host, "discord"):
+			var msg discordMessage
+			json.NewDecoder(r.Body).Decode(&msg)
+			posted["Discord"] = msg.Embeds[0].Description
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: make(http.Header)}, nil
+	})
+
+	notifiers := cfg.Notifiers(nil)
+	if len(notifiers) != 2 {
+		t.Fatalf("got %d notifiers, want Slack and Discord", len(notifiers))
+	}
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(context.Background(), []Article{article}, cfg); err != nil {
+			t.Fatalf("%s: %v", notifier.Name(), err)
+		}
+	}
+
+	tests := []struct {
+		notifier string
+		want     string
+	}{
+		{"Slack", "\n" + truncateText(summary, 10)},
+		{"Discord", "Tags: Stack\n" + truncateText(summary, 30)},
+	}
+	for _, tt := range tests {
+		if got := posted[tt.notifier]; !strings.HasSuffix(got, tt.want) {
+			t.Errorf("%s rendered %q, want it to end with %q", tt.notifier, got, tt.want)
+		}
+	}
+	// The email keeps its own, default, length
+	if html := generateHTMLEmail([]Article{article}, cfg.Location, cfg); !strings.Contains(html, summary) {
+		t.Error("email summary was truncated by a notifier setting")
+	}
+}
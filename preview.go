@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runPreview serves the rendered email at http://localhost:port until interrupted.
+// The input is re-read on every request, so refreshing the browser picks up changes.
+// No email is sent and no state is touched.
+func runPreview(ctx context.Context, cfg Config, input string, port int) error {
+	if input == "" {
+		latest, err := latestJSONOutput("fetched_articles")
+		if err != nil {
+			return err
+		}
+		input = latest
+	}
+
+	loc := cfg.Location
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		articles, err := readArticlesJSON(input)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		fmt.Fprint(w, generateHTMLEmail(articles, loc, cfg))
+	})
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf("localhost:%d", port),
+		Handler: handler,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	fmt.Printf("Previewing %s at http://%s (Ctrl-C to stop)\n", input, server.Addr)
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("preview server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	fmt.Println("\nShutting down preview server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down preview server: %w", err)
+	}
+	return nil
+}
+
+// latestJSONOutput finds the most recently modified JSON file in dir
+func latestJSONOutput(dir string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return "", err
+	}
+
+	var latest string
+	var latestMod time.Time
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latestMod) {
+			latest, latestMod = path, info.ModTime()
+		}
+	}
+
+	if latest == "" {
+		return "", fmt.Errorf("no JSON output found in %s; pass --input with an articles JSON file", dir)
+	}
+	return latest, nil
+}
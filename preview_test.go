@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunPreview(t *testing.T) {
+	inTempDir(t)
+	cfg := testConfig(t)
+	writeArticles := func(uuid string) {
+		data, err := json.Marshal([]Article{testArticle(uuid, "2024-05-01T10:00:00Z")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, "articles.json", string(data))
+	}
+	writeArticles("u1")
+
+	// Find a free port for the server
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runPreview(ctx, cfg, "articles.json", port) }()
+	defer cancel()
+
+	get := func() string {
+		t.Helper()
+		url := fmt.Sprintf("http://localhost:%d/", port)
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			resp, err := http.Get(url)
+			if err != nil {
+				if time.Now().After(deadline) {
+					t.Fatalf("preview server not reachable: %v", err)
+				}
+				continue
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			return string(body)
+		}
+	}
+
+	if page := get(); !strings.Contains(page, "Article u1") {
+		t.Errorf("preview does not render the input:\n%s", page)
+	}
+	// A refresh picks up the edited input
+	writeArticles("u2")
+	if page := get(); !strings.Contains(page, "Article u2") {
+		t.Errorf("refreshed preview does not show the new input:\n%s", page)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runPreview() = %v, want a clean shutdown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("preview server did not shut down")
+	}
+}
+
+func TestLatestJSONOutput(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := latestJSONOutput(dir); err == nil || !strings.Contains(err.Error(), "no JSON output found") {
+		t.Errorf("empty directory: error = %v, want no JSON output found", err)
+	}
+
+	older, newer := filepath.Join(dir, "older.json"), filepath.Join(dir, "newer.json")
+	writeFile(t, newer, "[]")
+	writeFile(t, older, "[]")
+	writeFile(t, filepath.Join(dir, "digest.txt"), "text")
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(older, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := latestJSONOutput(dir); err != nil || got != newer {
+		t.Errorf("latestJSONOutput() = %s, %v, want %s", got, err, newer)
+	}
+}
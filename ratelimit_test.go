@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterPacesScan(t *testing.T) {
+	newest := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	listing := listingArticles(450, newest)
+	const rps = 25
+	interval := time.Second / rps
+
+	var mu sync.Mutex
+	var starts []time.Time
+	srv := newGraphQLServer(t, func(w http.ResponseWriter, r *http.Request, body graphQLRequest) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		skip := int(body.Variables["skip"].(float64))
+		end := min(skip+int(body.Variables["first"].(float64)), len(listing))
+		w.Write(listingJSON(len(listing), listing[min(skip, end):end]))
+	})
+
+	// Four workers would otherwise request the later pages all at once
+	opts := clientOptions{Workers: 4, Limiter: newRateLimiter(rps), HTTPClient: serverClient(srv)}
+	articles, err := fetchArticlesAfterTime(context.Background(), newest.Add(-24*time.Hour), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != len(listing) {
+		t.Fatalf("fetched %d articles, want %d", len(articles), len(listing))
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	// Allow for the time between the limiter releasing a request and the server seeing it
+	const slack = 10 * time.Millisecond
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < interval-slack {
+			t.Errorf("requests %d and %d were %s apart, want at least %s", i, i+1, gap, interval)
+		}
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	limiter := newRateLimiter(0.1)
+	if err := limiter.wait(context.Background()); err != nil {
+		t.Fatalf("first wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := limiter.wait(ctx); err == nil {
+		t.Error("wait ignored the cancelled context")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled wait took %s", elapsed)
+	}
+
+	var unlimited *rateLimiter
+	if err := unlimited.wait(context.Background()); err != nil {
+		t.Errorf("nil limiter: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Read-later services accepted by READ_LATER_SERVICE
+const (
+	readLaterPocket     = "pocket"
+	readLaterInstapaper = "instapaper"
+)
+
+// API endpoints that save a URL to each read-later service
+const (
+	pocketAddURL     = "https://getpocket.com/v3/add"
+	instapaperAddURL = "https://www.instapaper.com/api/add"
+)
+
+// readLaterRPS paces saves so a large digest does not trip the services' rate limits
+const readLaterRPS = 2
+
+// parseReadLaterService validates a READ_LATER_SERVICE value; "" disables it
+func parseReadLaterService(v string) (string, error) {
+	switch s := strings.ToLower(strings.TrimSpace(v)); s {
+	case "", readLaterPocket, readLaterInstapaper:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown service %q: must be %s or %s", v, readLaterPocket, readLaterInstapaper)
+	}
+}
+
+// savedURLs tracks the article URLs saved to the read-later service, in the order
+// they were saved. It starts from the state and collects the URLs saved by a run.
+type savedURLs struct {
+	set  map[string]bool
+	list []string
+}
+
+func newSavedURLs(list []string) *savedURLs {
+	s := &savedURLs{set: make(map[string]bool, len(list)), list: append([]string(nil), list...)}
+	for _, u := range list {
+		s.set[u] = true
+	}
+	return s
+}
+
+func (s *savedURLs) has(u string) bool { return s.set[u] }
+
+func (s *savedURLs) add(u string) {
+	if !s.set[u] {
+		s.set[u] = true
+		s.list = append(s.list, u)
+	}
+}
+
+// recent returns at most max URLs, dropping the oldest first
+func (s *savedURLs) recent(max int) []string {
+	if len(s.list) > max {
+		return s.list[len(s.list)-max:]
+	}
+	return s.list
+}
+
+// ReadLaterNotifier saves each article's URL to Pocket or Instapaper. Articles
+// whose URL is in Saved are skipped; a failed save does not stop the others.
+type ReadLaterNotifier struct {
+	Service string // readLaterPocket or readLaterInstapaper
+
+	// Pocket credentials
+	PocketConsumerKey string
+	PocketAccessToken string
+	// Instapaper credentials
+	InstapaperUsername string
+	InstapaperPassword string
+
+	Saved  *savedURLs // URLs saved already; those saved by Notify are added
+	Client Doer       // nil means defaultHTTPClient
+}
+
+func (n ReadLaterNotifier) Name() string {
+	if n.Service == readLaterInstapaper {
+		return "Instapaper"
+	}
+	return "Pocket"
+}
+
+func (n ReadLaterNotifier) Notify(ctx context.Context, articles []Article, cfg Config) error {
+	saved := n.Saved
+	if saved == nil {
+		saved = newSavedURLs(nil)
+	}
+	client := httpClient(n.Client)
+	limiter := newRateLimiter(readLaterRPS)
+
+	skipped, failed := 0, 0
+	var firstErr error
+	for _, article := range articles {
+		link := articleURL(article)
+		if saved.has(link) {
+			skipped++
+			continue
+		}
+		if err := limiter.wait(ctx); err != nil {
+			return err
+		}
+
+		var err error
+		if n.Service == readLaterInstapaper {
+			err = n.saveToInstapaper(ctx, client, link, article.Title)
+		} else {
+			err = n.saveToPocket(ctx, client, link, article.Title)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to save %s to %s: %v\n", link, n.Name(), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+		saved.add(link)
+	}
+
+	if skipped > 0 {
+		fmt.Printf("Skipped %d articles already saved to %s.\n", skipped, n.Name())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d articles could not be saved: %w", failed, len(articles)-skipped, firstErr)
+	}
+	return nil
+}
+
+// saveToPocket adds a URL to Pocket with the v3 add API
+func (n ReadLaterNotifier) saveToPocket(ctx context.Context, client Doer, link, title string) error {
+	payload, err := json.Marshal(map[string]string{
+		"url":          link,
+		"title":        title,
+		"consumer_key": n.PocketConsumerKey,
+		"access_token": n.PocketAccessToken,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal pocket request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", pocketAddURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Pocket explains failures in the X-Error header rather than the body
+		if reason := resp.Header.Get("X-Error"); reason != "" {
+			return fmt.Errorf("pocket returned status %d: %s", resp.StatusCode, reason)
+		}
+		return fmt.Errorf("pocket returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// saveToInstapaper adds a URL to Instapaper with the simple API
+func (n ReadLaterNotifier) saveToInstapaper(ctx context.Context, client Doer, link, title string) error {
+	form := url.Values{"url": {link}, "title": {title}}
+	req, err := http.NewRequestWithContext(ctx, "POST", instapaperAddURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(n.InstapaperUsername, n.InstapaperPassword)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if reason := strings.TrimSpace(string(body)); reason != "" {
+			return fmt.Errorf("instapaper returned status %d: %s", resp.StatusCode, reason)
+		}
+		return fmt.Errorf("instapaper returned status %d", resp.StatusCode)
+	}
+	return nil
+}
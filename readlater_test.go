@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadLaterNotifier(t *testing.T) {
+	tests := []struct {
+		service  string
+		endpoint string
+		created  int // status of a successful save
+	}{
+		{readLaterPocket, pocketAddURL, http.StatusOK},
+		{readLaterInstapaper, instapaperAddURL, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			articles := []Article{
+				testArticle("u1", "2024-05-01T10:00:00Z"),
+				testArticle("u2", "2024-05-01T09:00:00Z"),
+				testArticle("u3", "2024-05-01T08:00:00Z"),
+			}
+			failing := articleURL(articles[1])
+
+			// The mock API fails the second article and records the URL of every call
+			var calls []string
+			client := doerFunc(func(r *http.Request) (*http.Response, error) {
+				if r.URL.String() != tt.endpoint {
+					t.Errorf("request to %s, want %s", r.URL, tt.endpoint)
+				}
+				var link string
+				if tt.service == readLaterPocket {
+					var body map[string]string
+					json.NewDecoder(r.Body).Decode(&body)
+					link = body["url"]
+					if body["consumer_key"] != "ck" || body["access_token"] != "at" {
+						t.Errorf("pocket credentials = %q, %q", body["consumer_key"], body["access_token"])
+					}
+				} else {
+					r.ParseForm()
+					link = r.PostForm.Get("url")
+					if user, pass, _ := r.BasicAuth(); user != "me" || pass != "pw" {
+						t.Errorf("instapaper credentials = %q, %q", user, pass)
+					}
+				}
+				calls = append(calls, link)
+
+				rec := httptest.NewRecorder()
+				if link == failing {
+					rec.Header().Set("X-Error", "rate limited")
+					rec.WriteHeader(http.StatusServiceUnavailable)
+				} else {
+					rec.WriteHeader(tt.created)
+				}
+				return rec.Result(), nil
+			})
+
+			saved := newSavedURLs([]string{articleURL(articles[0])})
+			notifier := ReadLaterNotifier{
+				Service:           tt.service,
+				PocketConsumerKey: "ck", PocketAccessToken: "at",
+				InstapaperUsername: "me", InstapaperPassword: "pw",
+				Saved:  saved,
+				Client: client,
+			}
+			err := notifier.Notify(context.Background(), articles, testConfig(t))
+
+			if got, want := strings.Join(calls, ","), failing+","+articleURL(articles[2]); got != want {
+				t.Errorf("calls = %s, want one per new article: %s", got, want)
+			}
+			if err == nil || !strings.Contains(err.Error(), "1 of 2 articles could not be saved") {
+				t.Errorf("Notify() error = %v, want the failed save reported", err)
+			}
+			if got, want := strings.Join(saved.list, ","), articleURL(articles[0])+","+articleURL(articles[2]); got != want {
+				t.Errorf("saved = %s, want %s", got, want)
+			}
+		})
+	}
+
+	// Unconfigured, no read-later notifier is set up
+	for _, notifier := range testConfig(t).Notifiers(nil) {
+		if _, ok := notifier.(ReadLaterNotifier); ok {
+			t.Error("read-later notifier set up without READ_LATER_SERVICE")
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// rssFeed is an RSS 2.0 document
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	PubDate     string    `xml:"pubDate,omitempty"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	Author      string  `xml:"author,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+}
+
+// rssGUID identifies an item by article UUID, which is not a URL
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// writeArticlesToRSS writes the articles as an RSS 2.0 feed with one item per article.
+// The channel's pubDate is the newest article's time.
+func writeArticlesToRSS(articles []Article, filename string, cfg Config) error {
+	items := make([]rssItem, 0, len(articles))
+	for _, article := range articles {
+		items = append(items, newRSSItem(article, cfg))
+	}
+	return writeRSSItems(items, filename, cfg)
+}
+
+// appendArticlesToRSS adds articles to an existing feed, skipping UUIDs it already
+// holds and keeping the newest first. A missing file is written in full. It returns
+// the number of articles added.
+func appendArticlesToRSS(articles []Article, filename string, cfg Config) (int, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return len(articles), writeArticlesToRSS(articles, filename, cfg)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read existing file: %w", err)
+	}
+
+	var existing rssFeed
+	if err := xml.Unmarshal(data, &existing); err != nil {
+		return 0, fmt.Errorf("failed to parse existing feed %s: %w", filename, err)
+	}
+
+	seen := make(map[string]bool, len(existing.Channel.Items))
+	for _, item := range existing.Channel.Items {
+		seen[item.GUID.Value] = true
+	}
+	fresh := excludeSeenArticles(articles, seen)
+	if len(fresh) == 0 {
+		return 0, nil
+	}
+
+	items := make([]rssItem, 0, len(fresh)+len(existing.Channel.Items))
+	for _, article := range fresh {
+		items = append(items, newRSSItem(article, cfg))
+	}
+	return len(fresh), writeRSSItems(append(items, existing.Channel.Items...), filename, cfg)
+}
+
+// newRSSItem maps an article to a feed item
+func newRSSItem(article Article, cfg Config) rssItem {
+	return rssItem{
+		Title:       article.Title,
+		Link:        articleURL(article),
+		Description: article.Summary,
+		Author:      authorName(article, cfg),
+		GUID:        rssGUID{IsPermaLink: "false", Value: article.UUID},
+		PubDate:     formatStringTimestamp(article.CreatedAt, time.RFC1123Z, cfg.Location),
+	}
+}
+
+// writeRSSItems writes a complete feed holding items
+func writeRSSItems(items []rssItem, filename string, cfg Config) error {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       fileTitle(cfg.RunLabel) + " - Latest Articles",
+			Link:        "https://leetcode.com/discuss/",
+			Description: "New articles from the LeetCode discuss section",
+			Items:       items,
+		},
+	}
+
+	var newest time.Time
+	for _, item := range items {
+		if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil && t.After(newest) {
+			newest = t
+		}
+	}
+	if !newest.IsZero() {
+		feed.Channel.PubDate = newest.Format(time.RFC1123Z)
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode feed: %w", err)
+	}
+
+	return writeOutput(filename, cfg.AtomicWrite, func(w io.Writer) {
+		io.WriteString(w, xml.Header)
+		w.Write(data)
+		io.WriteString(w, "\n")
+	})
+}
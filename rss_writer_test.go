@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRSSFeed(t *testing.T) {
+	cfg := testConfig(t)
+	filename := filepath.Join(t.TempDir(), "feed.xml")
+
+	older := testArticle("u1", "2024-05-01T08:00:00Z")
+	older.Title = `Graphs <BFS> & "DFS"`
+	older.Summary = "Use a queue & a visited set <always>."
+	newer := testArticle("u2", "2024-05-02T10:30:00+05:30")
+	if err := writeArticlesToRSS([]Article{older, newer}, filename, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	var feed rssFeed
+	if err := xml.Unmarshal([]byte(readFile(t, filename)), &feed); err != nil {
+		t.Fatalf("feed is not valid XML: %v", err)
+	}
+	if feed.Version != "2.0" || len(feed.Channel.Items) != 2 {
+		t.Fatalf("feed version %q with %d items, want 2.0 with 2", feed.Version, len(feed.Channel.Items))
+	}
+
+	item := feed.Channel.Items[0]
+	if item.Title != older.Title || item.Description != older.Summary || item.Link != articleURL(older) {
+		t.Errorf("item = %+v, want the article's title, summary and URL unchanged", item)
+	}
+	if item.Author != "author-u1" || item.GUID.Value != "u1" {
+		t.Errorf("item author %q, guid %q", item.Author, item.GUID.Value)
+	}
+	if pub, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil || !pub.Equal(time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("item pubDate %q is not the createdAt in RFC1123Z", item.PubDate)
+	}
+	if feed.Channel.PubDate != feed.Channel.Items[1].PubDate {
+		t.Errorf("channel pubDate %q, want the newest article's %q", feed.Channel.PubDate, feed.Channel.Items[1].PubDate)
+	}
+}
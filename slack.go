@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// slackMaxBlocks is the most blocks Slack accepts in one message; each message
+// spends one of them on its header
+const slackMaxBlocks = 50
+
+// slackHeaderMaxLen is the longest plain text Slack accepts in a header block
+const slackHeaderMaxLen = 150
+
+type slackText struct {
+	Type string `json:"type"` // "plain_text" or "mrkdwn"
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"` // "header" or "section"
+	Text *slackText `json:"text"`
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"` // shown in notifications
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// postToSlack posts the digest to a Slack incoming webhook using Block Kit: a
+// header followed by one section per article. Digests with more articles than
+// fit in one message are split across several posts.
+func postToSlack(ctx context.Context, client Doer, webhookURL string, articles []Article, format articleFormat, cfg Config) error {
+	perMessage := slackMaxBlocks - 1
+	parts := (len(articles) + perMessage - 1) / perMessage
+	title := fmt.Sprintf("📚 %s - %d New Articles", emailTitle(cfg.RunLabel), len(newArticles(articles)))
+
+	for part := 0; part < parts; part++ {
+		chunk := articles[part*perMessage : min((part+1)*perMessage, len(articles))]
+
+		header := title
+		if parts > 1 {
+			header = fmt.Sprintf("%s (%d/%d)", title, part+1, parts)
+		}
+		msg := slackMessage{
+			Text:   header,
+			Blocks: []slackBlock{{Type: "header", Text: &slackText{Type: "plain_text", Text: truncateText(header, slackHeaderMaxLen)}}},
+		}
+		for _, article := range chunk {
+			msg.Blocks = append(msg.Blocks, slackBlock{
+				Type: "section",
+				Text: &slackText{Type: "mrkdwn", Text: slackArticleText(article, format, cfg)},
+			})
+		}
+
+		if err := sendSlackMessage(ctx, client, webhookURL, msg); err != nil {
+			if part > 0 {
+				return fmt.Errorf("posted %d of %d messages: %w", part, parts, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// slackArticleText renders an article as a section's markdown: a link to the
+// article, the author and date, and the sections of the format
+func slackArticleText(article Article, format articleFormat, cfg Config) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "*<%s|%s>*\n", articleURL(article), escapeSlack(displayTitle(article.Title, cfg.TitleMaxLen)))
+	if author := authorName(article, cfg); author != "" {
+		fmt.Fprintf(&text, "by %s · ", escapeSlack(author))
+	}
+	text.WriteString(formatStringTimestamp(article.CreatedAt, cfg.DateFormat.DateTime, cfg.Location))
+	text.WriteString(statusSuffix(article, cfg))
+	for _, line := range chatArticleLines(article, format, cfg) {
+		fmt.Fprintf(&text, "\n%s", escapeSlack(line))
+	}
+	return text.String()
+}
+
+// escapeSlack escapes the characters Slack treats as markup in message text
+func escapeSlack(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// sendSlackMessage posts one message to the webhook
+func sendSlackMessage(ctx context.Context, client Doer, webhookURL string, msg slackMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &SlackError{Err: fmt.Errorf("failed to send request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &SlackError{StatusCode: resp.StatusCode, Reason: strings.TrimSpace(string(body))}
+	}
+	return nil
+}
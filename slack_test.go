@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostToSlack(t *testing.T) {
+	tests := []struct {
+		articles    int
+		wantBlocks  string // blocks in each message
+		wantHeaders []string
+	}{
+		{3, "4", []string{"📚 LeetCode Daily Digest - 3 New Articles"}},
+		{49, "50", []string{"📚 LeetCode Daily Digest - 49 New Articles"}},
+		{50, "50,2", []string{"📚 LeetCode Daily Digest - 50 New Articles (1/2)", "📚 LeetCode Daily Digest - 50 New Articles (2/2)"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.articles), func(t *testing.T) {
+			var messages []slackMessage
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var msg slackMessage
+				if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+					t.Errorf("payload is not JSON: %v", err)
+				}
+				messages = append(messages, msg)
+				w.Write([]byte("ok"))
+			}))
+			defer srv.Close()
+
+			cfg := testConfig(t)
+			articles := listingArticles(tt.articles, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+			articles[0].Title = "Heaps <and> stacks"
+			articles[0].Summary = "Priority queues & more."
+			if err := postToSlack(context.Background(), srv.Client(), srv.URL, articles, cfg.SlackFormat, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			var blocks, headers []string
+			for _, msg := range messages {
+				blocks = append(blocks, fmt.Sprint(len(msg.Blocks)))
+				headers = append(headers, msg.Blocks[0].Text.Text)
+				if msg.Blocks[0].Type != "header" || msg.Text != msg.Blocks[0].Text.Text {
+					t.Errorf("message does not open with its header: %+v", msg.Blocks[0])
+				}
+			}
+			if got := strings.Join(blocks, ","); got != tt.wantBlocks {
+				t.Errorf("blocks per message %s, want %s", got, tt.wantBlocks)
+			}
+			if got, want := strings.Join(headers, "\n"), strings.Join(tt.wantHeaders, "\n"); got != want {
+				t.Errorf("headers:\n%s\nwant:\n%s", got, want)
+			}
+
+			section := messages[0].Blocks[1].Text
+			want := "*<" + articleURL(articles[0]) + "|Heaps &lt;and&gt; stacks>*\nby author-u000 · 2024-05-01 15:30:00 IST\nPriority queues &amp; more."
+			if section.Type != "mrkdwn" || section.Text != want {
+				t.Errorf("first section %s %q, want mrkdwn %q", section.Type, section.Text, want)
+			}
+		})
+	}
+}
+
+func TestSlackErrors(t *testing.T) {
+	tests := []struct {
+		status      int
+		body        string
+		wantInvalid bool
+	}{
+		{http.StatusBadRequest, "invalid_payload", true},
+		{http.StatusNotFound, "no_service", false},
+		{http.StatusInternalServerError, "", false},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, tt.body, tt.status)
+		}))
+		cfg := testConfig(t)
+		err := postToSlack(context.Background(), srv.Client(), srv.URL, []Article{testArticle("u1", "2024-05-01T10:00:00Z")}, cfg.SlackFormat, cfg)
+		srv.Close()
+
+		var slackErr *SlackError
+		if !errors.As(err, &slackErr) || slackErr.StatusCode != tt.status || slackErr.Reason != tt.body {
+			t.Errorf("status %d: error = %#v, want a SlackError with status and reason %q", tt.status, err, tt.body)
+			continue
+		}
+		if got := slackErr.InvalidPayload(); got != tt.wantInvalid {
+			t.Errorf("status %d: InvalidPayload() = %v, want %v", tt.status, got, tt.wantInvalid)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// SMTPConfig holds the connection settings for the SMTP backend
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// SMTPSender sends email through an SMTP server with net/smtp
+type SMTPSender struct {
+	Config SMTPConfig
+}
+
+func (s SMTPSender) Send(ctx context.Context, msg emailMessage, to []string) error {
+	return sendEmailViaSMTP(ctx, s.Config, msg, to)
+}
+
+// sendEmailViaSMTP sends an HTML email over SMTP. Each recipient is offered
+// separately; rejected recipients are returned as a *RecipientError while the
+// message is still delivered to the rest.
+func sendEmailViaSMTP(ctx context.Context, cfg SMTPConfig, msg emailMessage, toEmails []string) error {
+	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	if cfg.Port == "465" {
+		// Implicit TLS
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: cfg.Host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start smtp session: %w", err)
+	}
+	defer client.Close()
+
+	// net/smtp has no context support, so close the connection on cancellation
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(msg.FromEmail); err != nil {
+		return fmt.Errorf("smtp server rejected sender %s: %w", msg.FromEmail, err)
+	}
+
+	recipientErr := &RecipientError{Rejected: make(map[string]error)}
+	for _, email := range toEmails {
+		if err := client.Rcpt(email); err != nil {
+			recipientErr.Rejected[email] = err
+			continue
+		}
+		recipientErr.Accepted = append(recipientErr.Accepted, email)
+	}
+
+	if recipientErr.AllRejected() {
+		client.Reset()
+		return recipientErr
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message data: %w", err)
+	}
+	if _, err := w.Write(buildMIMEMessage(msg, recipientErr.Accepted)); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp server did not accept the message: %w", err)
+	}
+
+	client.Quit()
+
+	if len(recipientErr.Rejected) > 0 {
+		return recipientErr
+	}
+	return nil
+}
+
+// buildMIMEMessage creates a multipart/alternative message with quoted-printable
+// plain-text and HTML bodies. Attachments wrap it in a multipart/mixed message.
+func buildMIMEMessage(msg emailMessage, toEmails []string) []byte {
+	var buf bytes.Buffer
+
+	from := msg.FromEmail
+	if msg.FromName != "" {
+		from = fmt.Sprintf("%s <%s>", mime.QEncoding.Encode("utf-8", msg.FromName), msg.FromEmail)
+	}
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(toEmails, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+
+	var body bytes.Buffer
+	alt := multipart.NewWriter(&body)
+	for _, p := range []struct{ contentType, content string }{
+		// Clients show the last alternative they support, so HTML goes last
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		part, _ := alt.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		writeQuotedPrintable(part, p.content)
+	}
+	alt.Close()
+	altType := "multipart/alternative; boundary=" + alt.Boundary()
+
+	if len(msg.Attachments) == 0 {
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n\r\n", altType)
+		buf.Write(body.Bytes())
+		return buf.Bytes()
+	}
+
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	part, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {altType}})
+	part.Write(body.Bytes())
+
+	for _, a := range msg.Attachments {
+		part, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		writeBase64Lines(part, a.Content)
+	}
+	mw.Close()
+
+	return buf.Bytes()
+}
+
+// writeQuotedPrintable writes s to w in quoted-printable encoding
+func writeQuotedPrintable(w io.Writer, s string) {
+	qp := quotedprintable.NewWriter(w)
+	qp.Write([]byte(s))
+	qp.Close()
+}
+
+// writeBase64Lines writes data as base64 wrapped at 76 characters, as MIME requires
+func writeBase64Lines(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		fmt.Fprintf(w, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	fmt.Fprintf(w, "%s\r\n", encoded)
+}
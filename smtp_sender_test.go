@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// smtpServer is a minimal SMTP server that rejects RCPT for the listed
+// addresses and records the messages it accepts
+type smtpServer struct {
+	addr     string
+	rejected map[string]bool
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func newSMTPServer(t *testing.T, rejected ...string) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &smtpServer{addr: ln.Addr().String(), rejected: make(map[string]bool)}
+	for _, r := range rejected {
+		s.rejected[r] = true
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	reply("220 localhost ESMTP test")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"), cmd == "RSET", cmd == "NOOP":
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			addr := strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<> ")
+			if s.rejected[addr] {
+				reply("550 5.1.1 No such user")
+			} else {
+				reply("250 OK")
+			}
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 OK queued")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+func TestSMTPRejectedRecipient(t *testing.T) {
+	tests := []struct {
+		name         string
+		to           []string
+		rejected     []string
+		wantAccepted []string
+		wantAll      bool
+		wantMessages int
+	}{
+		{
+			name:         "one rejected",
+			to:           []string{"a@example.com", "bad@example.com", "c@example.com"},
+			rejected:     []string{"bad@example.com"},
+			wantAccepted: []string{"a@example.com", "c@example.com"},
+			wantMessages: 1,
+		},
+		{
+			name:     "all rejected",
+			to:       []string{"bad@example.com"},
+			rejected: []string{"bad@example.com"},
+			wantAll:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSMTPServer(t, tt.rejected...)
+			host, port, _ := net.SplitHostPort(srv.addr)
+			msg := emailMessage{FromEmail: "digest@example.com", Subject: "Digest", Text: "hi", HTML: "<p>hi</p>"}
+
+			err := SMTPSender{Config: SMTPConfig{Host: host, Port: port}}.Send(context.Background(), msg, tt.to)
+
+			var recipientErr *RecipientError
+			if !errors.As(err, &recipientErr) {
+				t.Fatalf("Send() error = %v, want a *RecipientError", err)
+			}
+			if got := recipientErr.AllRejected(); got != tt.wantAll {
+				t.Errorf("AllRejected() = %v, want %v", got, tt.wantAll)
+			}
+			if got, want := strings.Join(recipientErr.Accepted, ","), strings.Join(tt.wantAccepted, ","); got != want {
+				t.Errorf("accepted = %q, want %q", got, want)
+			}
+			for _, r := range tt.rejected {
+				if recipientErr.Rejected[r] == nil {
+					t.Errorf("%s not reported as rejected", r)
+				}
+			}
+
+			messages := srv.received()
+			if len(messages) != tt.wantMessages {
+				t.Fatalf("server received %d messages, want %d", len(messages), tt.wantMessages)
+			}
+			if tt.wantMessages > 0 && !strings.Contains(messages[0], "To: "+strings.Join(tt.wantAccepted, ", ")) {
+				t.Errorf("message not addressed to the accepted recipients:\n%s", messages[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ArticleSource provides articles published after a cutoff time, or the latest
+// ones regardless of it. A source may return the articles it collected before
+// failing along with the error.
+type ArticleSource interface {
+	FetchArticlesAfter(ctx context.Context, cutoffTime time.Time) ([]Article, error)
+	FetchLatest(ctx context.Context, n int) ([]Article, error)
+}
+
+// LeetCodeSource fetches articles from the LeetCode GraphQL API
+type LeetCodeSource struct {
+	Options clientOptions
+}
+
+// FetchArticlesAfter fetches articles newer than cutoffTime from LeetCode
+func (s LeetCodeSource) FetchArticlesAfter(ctx context.Context, cutoffTime time.Time) ([]Article, error) {
+	return fetchArticlesAfterTime(ctx, cutoffTime, s.Options)
+}
+
+// FetchLatest fetches the n most recent articles from LeetCode
+func (s LeetCodeSource) FetchLatest(ctx context.Context, n int) ([]Article, error) {
+	return fetchLatestArticles(ctx, n, s.Options)
+}
+
+// FileSource reads articles from a local JSON file holding an array of Article
+type FileSource struct {
+	Path string
+}
+
+// FetchArticlesAfter reads the file and keeps articles newer than cutoffTime
+func (s FileSource) FetchArticlesAfter(_ context.Context, cutoffTime time.Time) ([]Article, error) {
+	articles, err := readArticlesJSON(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []Article
+	for _, article := range articles {
+		articleTime, err := parseArticleTime(article.CreatedAt)
+		if err != nil {
+			continue // Skip if we can't parse the time
+		}
+
+		if articleTime.After(cutoffTime) {
+			filtered = append(filtered, article)
+		}
+	}
+
+	sortArticlesNewestFirst(filtered)
+	return filtered, nil
+}
+
+// FetchLatest reads the file and keeps its n newest articles
+func (s FileSource) FetchLatest(ctx context.Context, n int) ([]Article, error) {
+	articles, err := s.FetchArticlesAfter(ctx, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return articles[:min(n, len(articles))], nil
+}
+
+// newArticleSource builds a source from the SOURCE setting.
+// An empty value or "leetcode" selects the API, "file:<path>" reads a local JSON file.
+func newArticleSource(spec string, opts clientOptions) (ArticleSource, error) {
+	switch {
+	case spec == "" || spec == "leetcode":
+		return LeetCodeSource{Options: opts}, nil
+	case strings.HasPrefix(spec, "file:"):
+		path := strings.TrimSpace(strings.TrimPrefix(spec, "file:"))
+		if path == "" {
+			return nil, fmt.Errorf("file source requires a path, e.g. file:articles.json")
+		}
+		return FileSource{Path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown source %q", spec)
+	}
+}
+
+// readArticlesJSON reads a JSON array of Article from a file
+func readArticlesJSON(path string) ([]Article, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var articles []Article
+	if err := json.Unmarshal(data, &articles); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, describeJSONError(data, err))
+	}
+	return articles, nil
+}
+
+// describeJSONError turns a decoding error into a message pointing at the bad input
+func describeJSONError(data []byte, err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		line, col := lineAndColumn(data, syntaxErr.Offset)
+		return fmt.Sprintf("invalid JSON at line %d, column %d: %v", line, col, err)
+	case errors.As(err, &typeErr) && typeErr.Field == "":
+		return fmt.Sprintf("expected a JSON array of articles, found %s", typeErr.Value)
+	case errors.As(err, &typeErr):
+		line, col := lineAndColumn(data, typeErr.Offset)
+		return fmt.Sprintf("field %q at line %d, column %d must be %s, found %s", typeErr.Field, line, col, typeErr.Type, typeErr.Value)
+	default:
+		return err.Error()
+	}
+}
+
+// lineAndColumn converts a byte offset into a 1-based line and column
+func lineAndColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// validateArticles checks that articles loaded from a file can be rendered into a digest
+func validateArticles(articles []Article) error {
+	if len(articles) == 0 {
+		return errors.New("no articles found")
+	}
+
+	for i, article := range articles {
+		if missing := missingFields(article); len(missing) > 0 {
+			return fmt.Errorf("article %d is missing %s", i+1, strings.Join(missing, ", "))
+		}
+
+		if _, err := parseArticleTime(article.CreatedAt); err != nil {
+			return fmt.Errorf("article %d (%s) has an invalid createdAt %q", i+1, article.UUID, article.CreatedAt)
+		}
+	}
+	return nil
+}
+
+// missingFields lists the fields an article needs for its title and link but
+// lacks. The UUID is not among them: the API omits it for some articles.
+func missingFields(article Article) []string {
+	var missing []string
+	if article.Title == "" {
+		missing = append(missing, "title")
+	}
+	if article.Slug == "" {
+		missing = append(missing, "slug")
+	}
+	if article.TopicId == 0 {
+		missing = append(missing, "topicId")
+	}
+	return missing
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileSourceWritePath(t *testing.T) {
+	path := testdataPath(t, "articles.json")
+	cfg := testConfig(t)
+	inTempDir(t)
+
+	source, err := newArticleSource("file:"+path, cfg.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cutoff := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	articles, err := source.FetchArticlesAfter(context.Background(), cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The article from before the cutoff is dropped and the rest are newest first
+	var uuids []string
+	for _, article := range articles {
+		uuids = append(uuids, article.UUID)
+	}
+	want := []string{"f0002-0000-4000-8000-000000000000", "f0001-0000-4000-8000-000000000000"}
+	if strings.Join(uuids, ",") != strings.Join(want, ",") {
+		t.Fatalf("FetchArticlesAfter = %v, want %v", uuids, want)
+	}
+
+	filename, err := writeDigestFile(cfg, articles, cfg.Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir := filepath.Dir(filename); dir != "fetched_articles" {
+		t.Errorf("file written to %s, want fetched_articles", dir)
+	}
+	content := readFile(t, filename)
+	for _, want := range []string{
+		"LeetCode Discuss - Latest 2 Articles\n",
+		"UUID: f0001-0000-4000-8000-000000000000\n",
+		"Title: Amazon SDE II onsite\n",
+		"URL: https://leetcode.com/discuss/post/7000001/two-pointers-explained/\n",
+		"  - Two Pointers (two-pointers) [ALGORITHM]\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("file does not contain %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "An old post") {
+		t.Error("file contains the article from before the cutoff")
+	}
+}
+
+func TestFileSourceErrors(t *testing.T) {
+	if _, err := newArticleSource("file:", clientOptions{}); err == nil {
+		t.Error("file source without a path was accepted")
+	}
+	source := FileSource{Path: filepath.Join(t.TempDir(), "missing.json")}
+	if _, err := source.FetchArticlesAfter(context.Background(), time.Time{}); err == nil {
+		t.Error("missing file did not fail")
+	}
+}
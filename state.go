@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultStateFile = "last_processed_state.json"
+	legacyStateFile  = "last_processed_timestamp.txt"
+
+	maxSeenUUIDs = 1000        // recently reported articles kept in the state, oldest dropped first
+	maxSavedURLs = 1000        // recently saved read-later URLs kept in the state, oldest dropped first
+	stateOverlap = time.Minute // how far before the cutoff a run fetches again
+)
+
+// State is what a run leaves behind for the next one
+type State struct {
+	LastProcessed time.Time `json:"lastProcessed"`
+	// SeenUUIDs are the most recently reported articles, oldest first, identified
+	// by their UUID or whichever field DEDUP_KEY names. The next run overlaps the
+	// cutoff a little and skips these, so articles sharing the cutoff's second are
+	// neither lost nor reported twice.
+	SeenUUIDs []string `json:"seenUuids,omitempty"`
+	// LastRun is when the last run started, recorded for MIN_RUN_INTERVAL
+	LastRun time.Time `json:"lastRun,omitempty"`
+	// SavedURLs are the article URLs most recently saved to READ_LATER_SERVICE,
+	// oldest first, so an article is never saved twice
+	SavedURLs []string `json:"savedUrls,omitempty"`
+}
+
+// readState reads the state file. A missing file means no previous run, except
+// that the default file is migrated from the legacy timestamp file if that exists.
+func readState(path string) (State, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && path == defaultStateFile {
+		return migrateLegacyState(path)
+	}
+	if os.IsNotExist(err) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("failed to read state file: %w", err)
+	}
+	return parseState(data)
+}
+
+// parseState accepts both the JSON state and the legacy bare RFC3339 timestamp
+func parseState(data []byte) (State, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return State{}, nil
+	}
+
+	if data[0] != '{' {
+		t, err := time.Parse(time.RFC3339, string(data))
+		if err != nil {
+			return State{}, fmt.Errorf("failed to parse timestamp: %w", err)
+		}
+		return State{LastProcessed: t}, nil
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	return state, nil
+}
+
+// migrateLegacyState converts the legacy timestamp file into the JSON state at path
+func migrateLegacyState(path string) (State, error) {
+	data, err := os.ReadFile(legacyStateFile)
+	if os.IsNotExist(err) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("failed to read timestamp file: %w", err)
+	}
+
+	state, err := parseState(data)
+	if err != nil || state.LastProcessed.IsZero() {
+		return state, err
+	}
+	if err := writeState(path, state); err != nil {
+		return State{}, fmt.Errorf("failed to migrate %s: %w", legacyStateFile, err)
+	}
+	fmt.Printf("Migrated %s to %s\n", legacyStateFile, path)
+	return state, nil
+}
+
+// writeState writes the state file, creating the parent directory if needed. It
+// always goes through a temporary file, so a crash mid-write leaves the previous
+// state in place rather than a truncated one.
+func writeState(path string, state State) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create state directory %s: %w", dir, err)
+		}
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return writeOutput(path, true, func(w io.Writer) {
+		w.Write(append(data, '\n'))
+	})
+}
+
+// seen returns SeenUUIDs as a set
+func (s State) seen() map[string]bool {
+	set := make(map[string]bool, len(s.SeenUUIDs))
+	for _, uuid := range s.SeenUUIDs {
+		set[uuid] = true
+	}
+	return set
+}
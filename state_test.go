@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteStateCreatesDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "var", "lib", "lcdigest", "state.json")
+	want := State{LastProcessed: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	if err := writeState(path, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.LastProcessed.Equal(want.LastProcessed) {
+		t.Errorf("read back %v, want %v", got.LastProcessed, want.LastProcessed)
+	}
+}
+
+func TestWriteStateDirectoryError(t *testing.T) {
+	// A file where the directory should be makes MkdirAll fail
+	blocker := filepath.Join(t.TempDir(), "state")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := writeState(filepath.Join(blocker, "nested", "state.json"), State{})
+	if err == nil || !strings.Contains(err.Error(), "failed to create state directory") {
+		t.Errorf("writeState error = %v, want one naming the state directory", err)
+	}
+}
+
+func TestReadState(t *testing.T) {
+	cutoff := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		files        map[string]string
+		want         State
+		wantMigrated bool
+	}{
+		{
+			name: "first run",
+			want: State{},
+		},
+		{
+			name:         "legacy timestamp only",
+			files:        map[string]string{legacyStateFile: "2024-05-01T10:00:00Z\n"},
+			want:         State{LastProcessed: cutoff},
+			wantMigrated: true,
+		},
+		{
+			name: "JSON wins over legacy",
+			files: map[string]string{
+				defaultStateFile: `{"lastProcessed":"2024-05-01T10:00:00Z","seenUuids":["a","b"]}`,
+				legacyStateFile:  "2023-01-01T00:00:00Z",
+			},
+			want: State{LastProcessed: cutoff, SeenUUIDs: []string{"a", "b"}},
+		},
+		{
+			name:  "bare timestamp in the JSON file",
+			files: map[string]string{defaultStateFile: "2024-05-01T10:00:00Z"},
+			want:  State{LastProcessed: cutoff},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			for name, content := range tt.files {
+				writeFile(t, name, content)
+			}
+
+			got, err := readState(defaultStateFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.LastProcessed.Equal(tt.want.LastProcessed) || strings.Join(got.SeenUUIDs, ",") != strings.Join(tt.want.SeenUUIDs, ",") {
+				t.Errorf("readState() = %+v, want %+v", got, tt.want)
+			}
+
+			if !tt.wantMigrated {
+				return
+			}
+			migrated, err := parseState([]byte(readFile(t, defaultStateFile)))
+			if err != nil || !migrated.LastProcessed.Equal(tt.want.LastProcessed) {
+				t.Errorf("migrated state = %+v, %v, want %v", migrated, err, tt.want.LastProcessed)
+			}
+			if !strings.HasPrefix(readFile(t, defaultStateFile), "{") {
+				t.Error("migrated state file is not JSON")
+			}
+		})
+	}
+}
+
+func TestWriteStateFailureKeepsState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	previous := State{LastProcessed: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), SeenUUIDs: []string{"a"}}
+	if err := writeState(path, previous); err != nil {
+		t.Fatal(err)
+	}
+	before := readFile(t, path)
+
+	// Fail the write the way writeState makes it, halfway through the new state
+	next, _ := json.MarshalIndent(State{LastProcessed: previous.LastProcessed.Add(time.Hour)}, "", "  ")
+	err := writeOutput(path, true, func(w io.Writer) {
+		w.Write(next[:len(next)/2])
+		w.(*errWriter).err = errors.New("no space left on device")
+		w.Write(next[len(next)/2:])
+	})
+	if err == nil {
+		t.Fatal("the simulated write failure was not reported")
+	}
+	if got := readFile(t, path); got != before {
+		t.Errorf("state file changed by the failed write:\n%s\nwant\n%s", got, before)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d files, want the temp file removed", len(entries))
+	}
+
+	// A temp file left by a process killed mid-write is not read as the state
+	writeFile(t, filepath.Join(dir, ".state.json.tmp-123"), string(next[:len(next)/2]))
+	got, err := readState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.LastProcessed.Equal(previous.LastProcessed) || strings.Join(got.SeenUUIDs, ",") != "a" {
+		t.Errorf("readState() = %+v, want the previous state", got)
+	}
+}
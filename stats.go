@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// articleSummary holds aggregate figures about a set of articles
+type articleSummary struct {
+	Count           int
+	DistinctAuthors int    // articles without an author name are not counted
+	TopTag          string // most frequent tag name, alphabetically first on a tie
+	TotalReactions  int
+}
+
+// summarizeArticles computes aggregate figures for articles
+func summarizeArticles(articles []Article) articleSummary {
+	summary := articleSummary{Count: len(articles)}
+
+	authors := make(map[string]bool)
+	tagCounts := make(map[string]int)
+	for _, article := range articles {
+		if name := article.Author.UserName; name != "" {
+			authors[name] = true
+		}
+		summary.TotalReactions += totalReactions(article)
+		for _, tag := range article.Tags {
+			tagCounts[tag.Name]++
+		}
+	}
+	summary.DistinctAuthors = len(authors)
+
+	for name, n := range tagCounts {
+		top := tagCounts[summary.TopTag]
+		if n > top || (n == top && name < summary.TopTag) {
+			summary.TopTag = name
+		}
+	}
+	return summary
+}
+
+// statsRecord is one line of the STATS_JSONL file
+type statsRecord struct {
+	Date            string  `json:"date"`
+	ArticleCount    int     `json:"articleCount"`
+	DistinctAuthors int     `json:"distinctAuthors"`
+	TopTag          string  `json:"topTag"`
+	TotalReactions  int     `json:"totalReactions"`
+	FetchSeconds    float64 `json:"fetchSeconds"`
+}
+
+// appendStats appends a stats record for a run to path, one JSON object per line
+func appendStats(path string, articles []Article, fetchDuration time.Duration, now time.Time) (err error) {
+	summary := summarizeArticles(articles)
+	line, err := json.Marshal(statsRecord{
+		Date:            now.Format(time.RFC3339),
+		ArticleCount:    summary.Count,
+		DistinctAuthors: summary.DistinctAuthors,
+		TopTag:          summary.TopTag,
+		TotalReactions:  summary.TotalReactions,
+		FetchSeconds:    fetchDuration.Round(time.Millisecond).Seconds(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode stats: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create stats directory %s: %w", dir, err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open stats file: %w", err)
+	}
+	defer closeWithError(file, &err)
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write stats: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeArticles(t *testing.T) {
+	articles := []Article{
+		testArticle("u1", "2024-05-01T10:00:00Z"),
+		testArticle("u2", "2024-05-01T09:00:00Z"),
+		testArticle("u3", "2024-05-01T08:00:00Z"),
+		testArticle("u4", "2024-05-01T07:00:00Z"),
+	}
+	articles[1].Author.UserName = articles[0].Author.UserName
+	articles[2].Author.UserName = ""
+	articles[3].Author.UserName = ""
+	articles[0].Tags = []Tag{{Name: "Graph"}, {Name: "Array"}}
+	articles[1].Tags = []Tag{{Name: "Array"}, {Name: "Graph"}}
+	articles[2].Reactions = []Reaction{{ReactionType: "UPVOTE", Count: 5}, {ReactionType: "AWESOME", Count: 2}}
+
+	got := summarizeArticles(articles)
+	want := articleSummary{Count: 4, DistinctAuthors: 1, TopTag: "Array", TotalReactions: 7}
+	if got != want {
+		t.Errorf("summarizeArticles() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAppendStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dash", "stats.jsonl")
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	article := testArticle("u1", "2024-05-01T08:00:00Z")
+	article.Tags = []Tag{{Name: "Graph"}}
+	article.Reactions = []Reaction{{ReactionType: "UPVOTE", Count: 4}}
+	if err := appendStats(path, []Article{article}, 1500*time.Millisecond, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendStats(path, nil, 0, now.Add(24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(readFile(t, path), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("stats file has %d lines, want one per run", len(lines))
+	}
+	var got statsRecord
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("line %q is not JSON: %v", lines[0], err)
+	}
+	want := statsRecord{Date: "2024-05-01T09:00:00Z", ArticleCount: 1, DistinctAuthors: 1, TopTag: "Graph", TotalReactions: 4, FetchSeconds: 1.5}
+	if got != want {
+		t.Errorf("record = %+v, want %+v", got, want)
+	}
+}
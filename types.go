@@ -7,12 +7,22 @@ type Article struct {
 	Title       string     `json:"title"`
 	Slug        string     `json:"slug"`
 	Summary     string     `json:"summary"`
+	Content     string     `json:"content,omitempty"` // full body, only fetched with -full-content
 	Author      Author     `json:"author"`
 	CreatedAt   string     `json:"createdAt"`
 	UpdatedAt   string     `json:"updatedAt"`
 	ArticleType string     `json:"articleType"`
+	Thumbnail   string     `json:"thumbnail"`
 	Tags        []Tag      `json:"tags"`
 	Reactions   []Reaction `json:"reactions"`
+
+	// Engagement counts; zero when the API leaves them out
+	ViewCount    int `json:"viewCount"`
+	CommentCount int `json:"commentCount"`
+
+	// AlreadySeen is set locally on articles shown by ALWAYS_SHOW_LATEST that an
+	// earlier run already reported
+	AlreadySeen bool `json:"alreadySeen,omitempty"`
 }
 
 // Author represents the article author (only userName needed)
@@ -33,14 +43,24 @@ type Tag struct {
 	TagType string `json:"tagType"`
 }
 
-// ArticlesResponse represents the GraphQL response for articles
+// ArticlesResponse represents the GraphQL response for articles. The listing is
+// nil when the response carries no data for it, typically alongside Errors.
 type ArticlesResponse struct {
 	Data struct {
-		UgcArticleDiscussionArticles struct {
+		UgcArticleDiscussionArticles *struct {
 			TotalNum int `json:"totalNum"`
 			Edges    []struct {
 				Node Article `json:"node"`
 			} `json:"edges"`
 		} `json:"ugcArticleDiscussionArticles"`
 	} `json:"data"`
+	Errors []GraphQLError `json:"errors"`
+}
+
+// GraphQLError is an entry of the errors array GraphQL returns, even with status 200
+type GraphQLError struct {
+	Message    string `json:"message"`
+	Extensions struct {
+		Code string `json:"code"`
+	} `json:"extensions"`
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFixtures(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		wantUUIDs string
+		wantTotal int
+		wantError string
+	}{
+		{"discuss_page1.json", "a0004-0000-4000-8000-000000000000,a0003-0000-4000-8000-000000000000", 4, ""},
+		{"discuss_page2.json", "a0002-0000-4000-8000-000000000000,a0001-0000-4000-8000-000000000000", 4, ""},
+		{"discuss_empty.json", "", 0, ""},
+		{"discuss_null_author.json", "a0005-0000-4000-8000-000000000000", 1, ""},
+		{"discuss_epoch_timestamps.json", "a0006-0000-4000-8000-000000000000", 1, ""},
+		{"discuss_error.json", "", 0, `Cannot query field "reactions"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			resp := loadFixture(t, tt.fixture)
+
+			if tt.wantError != "" {
+				if resp.Data.UgcArticleDiscussionArticles != nil {
+					t.Error("error response has data")
+				}
+				if !strings.Contains(graphQLErrorMessages(resp.Errors), tt.wantError) {
+					t.Errorf("errors = %+v, want %q", resp.Errors, tt.wantError)
+				}
+				return
+			}
+
+			listing := resp.Data.UgcArticleDiscussionArticles
+			if listing == nil {
+				t.Fatal("response has no listing")
+			}
+			if listing.TotalNum != tt.wantTotal {
+				t.Errorf("totalNum = %d, want %d", listing.TotalNum, tt.wantTotal)
+			}
+			var articles []Article
+			for _, edge := range listing.Edges {
+				articles = append(articles, edge.Node)
+			}
+			if got := uuidsOf(articles); got != tt.wantUUIDs {
+				t.Errorf("uuids = %s, want %s", got, tt.wantUUIDs)
+			}
+
+			for _, article := range articles {
+				if article.TopicId == 0 || article.Title == "" || article.Slug == "" || article.ArticleType == "" {
+					t.Errorf("%s: core fields not populated: %+v", article.UUID, article)
+				}
+				if len(article.Reactions) != 1 || article.Reactions[0].Count == 0 {
+					t.Errorf("%s: reactions = %+v", article.UUID, article.Reactions)
+				}
+				if _, err := parseArticleTime(article.CreatedAt); err != nil {
+					t.Errorf("%s: createdAt: %v", article.UUID, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFixtureNullAuthor(t *testing.T) {
+	article := loadFixture(t, "discuss_null_author.json").Data.UgcArticleDiscussionArticles.Edges[0].Node
+	if article.Author.UserName != "" {
+		t.Errorf("null author decoded as %q", article.Author.UserName)
+	}
+	if len(article.Tags) != 1 {
+		t.Errorf("tags = %+v", article.Tags)
+	}
+}
+
+func TestFixtureEpochTimestamps(t *testing.T) {
+	article := loadFixture(t, "discuss_epoch_timestamps.json").Data.UgcArticleDiscussionArticles.Edges[0].Node
+	got, err := parseArticleTime(article.CreatedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("createdAt %s parsed as %s, want %s", article.CreatedAt, got, want)
+	}
+	if ms, err := parseArticleTime("1714557600000"); err != nil || !ms.Equal(got) {
+		t.Errorf("epoch milliseconds parsed as %s, %v", ms, err)
+	}
+}
@@ -1,49 +1,247 @@
 package main
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"html"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-const lastTimestampFile = "last_processed_timestamp.txt"
+// defaultLocation is India Standard Time, used for displayed dates unless -tz is given
+var defaultLocation = time.FixedZone("IST", 5*3600+30*60)
 
-// readLastProcessedTimestamp reads the last processed timestamp from file
-func readLastProcessedTimestamp() (time.Time, error) {
-	data, err := os.ReadFile(lastTimestampFile)
+// dateFormat holds the layouts used to display dates
+type dateFormat struct {
+	Date     string // calendar dates, such as the email subtitle
+	DateTime string // article and run timestamps
+}
+
+// defaultDateFormat is used when no LOCALE is configured
+var defaultDateFormat = dateFormat{
+	Date:     "January 2, 2006",
+	DateTime: "2006-01-02 15:04:05 MST",
+}
+
+// localeDateFormats are the supported LOCALE presets; other locales use isoDateFormat
+var localeDateFormats = map[string]dateFormat{
+	"en-us": {Date: "January 2, 2006", DateTime: "Jan 2, 2006 3:04 PM MST"},
+	"en-gb": {Date: "2 January 2006", DateTime: "02/01/2006 15:04 MST"},
+}
+
+var isoDateFormat = dateFormat{
+	Date:     "2006-01-02",
+	DateTime: "2006-01-02 15:04:05 MST",
+}
+
+// dateFormatForLocale picks the preset for a locale such as "en-GB" or "en_GB"
+func dateFormatForLocale(locale string) dateFormat {
+	if locale == "" {
+		return defaultDateFormat
+	}
+	key := strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if f, ok := localeDateFormats[key]; ok {
+		return f
+	}
+	return isoDateFormat
+}
+
+// formatStringTimestamp formats an ISO timestamp string in loc using the given layout
+func formatStringTimestamp(ts string, layout string, loc *time.Location) string {
+	t, err := parseArticleTime(ts)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// File doesn't exist, return zero time
-			return time.Time{}, nil
+		return ts
+	}
+	return t.In(loc).Format(layout)
+}
+
+// naiveTimeLayouts are timestamp forms without a zone offset, read as UTC
+var naiveTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+var naiveTimeWarning sync.Once
+
+// epochTimestamp matches Unix timestamps in seconds (10 digits) or milliseconds (13)
+var epochTimestamp = regexp.MustCompile(`^\d{10}(\d{3})?$`)
+
+// parseArticleTime parses an article timestamp. The API sends RFC 3339; Unix
+// epoch seconds or milliseconds are accepted too, and a timestamp without a
+// zone offset is assumed to be UTC, with a warning the first time.
+func parseArticleTime(ts string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err == nil {
+		return t, nil
+	}
+	if epochTimestamp.MatchString(ts) {
+		n, _ := strconv.ParseInt(ts, 10, 64)
+		if len(ts) == 13 {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	for _, layout := range naiveTimeLayouts {
+		if naive, naiveErr := time.Parse(layout, ts); naiveErr == nil {
+			naiveTimeWarning.Do(func() {
+				fmt.Fprintf(os.Stderr, "Warning: Timestamp %q has no time zone; assuming UTC\n", ts)
+			})
+			return naive, nil
 		}
-		return time.Time{}, fmt.Errorf("failed to read timestamp file: %w", err)
 	}
+	return time.Time{}, err
+}
+
+// sleepContext waits for d, returning early with ctx's error if it is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-	timestampStr := strings.TrimSpace(string(data))
-	if timestampStr == "" {
-		return time.Time{}, nil
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	t, err := time.Parse(time.RFC3339, timestampStr)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to parse timestamp: %w", err)
+// articleURL returns the discuss page URL of an article
+func articleURL(a Article) string {
+	return fmt.Sprintf("https://leetcode.com/discuss/post/%d/%s/", a.TopicId, a.Slug)
+}
+
+// authorName returns the name to display for an article's author. With
+// ANONYMIZE_AUTHORS the name is replaced by a stable pseudonym, so the same
+// author always maps to the same "user-<hash>" without revealing the name.
+func authorName(a Article, cfg Config) string {
+	if !cfg.AnonymizeAuthors || a.Author.UserName == "" {
+		return a.Author.UserName
+	}
+	mac := hmac.New(sha256.New, []byte(cfg.AnonymizeSalt))
+	mac.Write([]byte(a.Author.UserName))
+	return "user-" + hex.EncodeToString(mac.Sum(nil))[:10]
+}
+
+// totalReactions sums the counts of all reactions on an article
+func totalReactions(a Article) int {
+	total := 0
+	for _, reaction := range a.Reactions {
+		total += reaction.Count
 	}
+	return total
+}
 
-	return t, nil
+// engagementParts describes an article's view and comment counts, such as
+// "120 views", leaving out the ones that are zero
+func engagementParts(a Article) []string {
+	var parts []string
+	if a.ViewCount > 0 {
+		parts = append(parts, plural(a.ViewCount, "view"))
+	}
+	if a.CommentCount > 0 {
+		parts = append(parts, plural(a.CommentCount, "comment"))
+	}
+	return parts
 }
 
-// writeLastProcessedTimestamp writes the last processed timestamp to file
-func writeLastProcessedTimestamp(t time.Time) error {
-	return os.WriteFile(lastTimestampFile, []byte(t.Format(time.RFC3339)), 0644)
+// plural formats a count with its noun, adding an "s" unless the count is one
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
 }
 
-// formatStringTimestamp formats an ISO timestamp string to IST
-func formatStringTimestamp(ts string) string {
-	t, err := time.Parse(time.RFC3339, ts)
-	if err != nil {
-		return ts
+// mergeReactions combines entries of the same reaction type, which the API
+// occasionally repeats, into one with the summed count. Types keep the order and
+// spelling of their first entry.
+func mergeReactions(reactions []Reaction) []Reaction {
+	index := make(map[string]int, len(reactions))
+	merged := make([]Reaction, 0, len(reactions))
+	for _, reaction := range reactions {
+		key := normalizeReactionType(reaction.ReactionType)
+		if i, ok := index[key]; ok {
+			merged[i].Count += reaction.Count
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, reaction)
+	}
+	return merged
+}
+
+// sortArticlesNewestFirst orders articles by creation time, newest first, breaking
+// ties by UUID so the result does not depend on how the articles were fetched
+func sortArticlesNewestFirst(articles []Article) {
+	times := make(map[string]time.Time, len(articles))
+	for _, article := range articles {
+		t, _ := parseArticleTime(article.CreatedAt)
+		times[article.CreatedAt] = t
+	}
+
+	sort.SliceStable(articles, func(i, j int) bool {
+		ti, tj := times[articles[i].CreatedAt], times[articles[j].CreatedAt]
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return articles[i].UUID < articles[j].UUID
+	})
+}
+
+// defaultReactionFormat renders reactions as "UPVOTE: 12"
+const defaultReactionFormat = "{type}: {count}"
+
+// formatReaction renders a reaction using a format with {type} and {count} placeholders
+func formatReaction(format string, r Reaction) string {
+	return strings.NewReplacer("{type}", r.ReactionType, "{count}", strconv.Itoa(r.Count)).Replace(format)
+}
+
+// visibleTags returns the tags to display and how many were left out; max 0 shows all
+func visibleTags(tags []Tag, max int) ([]Tag, int) {
+	if max <= 0 || len(tags) <= max {
+		return tags, 0
+	}
+	return tags[:max], len(tags) - max
+}
+
+// HTML_STRIP_MODE values: how plainSummary turns a summary into text
+const (
+	stripNone       = "none"       // leave the summary as the API sent it (the default)
+	stripBasic      = "basic"      // remove tags only
+	stripAggressive = "aggressive" // also decode entities and collapse whitespace
+)
+
+// parseHTMLStripMode validates an HTML_STRIP_MODE value; unset means stripNone
+func parseHTMLStripMode(v string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(v)); mode {
+	case "":
+		return stripNone, nil
+	case stripNone, stripBasic, stripAggressive:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q: must be %s, %s or %s", v, stripNone, stripBasic, stripAggressive)
+	}
+}
+
+var htmlTag = regexp.MustCompile(`<[^<>]*>`)
+
+// plainSummary converts a summary that may contain HTML to plain text as mode
+// says. The aggressive mode replaces tags with spaces, so words in adjacent
+// elements stay apart, before collapsing all whitespace. stripNone returns the
+// summary unchanged.
+func plainSummary(summary, mode string) string {
+	switch mode {
+	case stripBasic:
+		return htmlTag.ReplaceAllString(summary, "")
+	case stripAggressive:
+		text := html.UnescapeString(htmlTag.ReplaceAllString(summary, " "))
+		return strings.Join(strings.Fields(text), " ")
+	default:
+		return summary
 	}
-	ist := time.FixedZone("IST", 5*3600+30*60)
-	return t.In(ist).Format("2006-01-02 15:04:05 MST")
 }
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortArticlesNewestFirst(t *testing.T) {
+	canonical := []Article{
+		testArticle("d", "2024-05-02T10:00:00Z"),
+		testArticle("b", "2024-05-01T12:00:00+00:00"), // same instant as c, ordered by UUID
+		testArticle("c", "2024-05-01T17:30:00+05:30"),
+		testArticle("a", "2024-05-01T08:00:00Z"),
+	}
+	want := uuidsOf(canonical)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 20; i++ {
+		shuffled := append([]Article(nil), canonical...)
+		rng.Shuffle(len(shuffled), func(a, b int) { shuffled[a], shuffled[b] = shuffled[b], shuffled[a] })
+
+		sortArticlesNewestFirst(shuffled)
+		if got := uuidsOf(shuffled); got != want {
+			t.Fatalf("sorted order %s, want %s", got, want)
+		}
+	}
+}
+
+func TestLocaleDateFormats(t *testing.T) {
+	ts := "2024-05-01T14:05:09Z"
+	utc := time.UTC
+	tests := []struct {
+		locale       string
+		wantDate     string
+		wantDateTime string
+	}{
+		{"en-US", "May 1, 2024", "May 1, 2024 2:05 PM UTC"},
+		{"en_GB", "1 May 2024", "01/05/2024 14:05 UTC"},
+		{"fr-FR", "2024-05-01", "2024-05-01 14:05:09 UTC"},
+	}
+	for _, tt := range tests {
+		format := dateFormatForLocale(tt.locale)
+		created, _ := parseArticleTime(ts)
+		if got := created.Format(format.Date); got != tt.wantDate {
+			t.Errorf("%s date = %q, want %q", tt.locale, got, tt.wantDate)
+		}
+		if got := formatStringTimestamp(ts, format.DateTime, utc); got != tt.wantDateTime {
+			t.Errorf("%s date and time = %q, want %q", tt.locale, got, tt.wantDateTime)
+		}
+	}
+}
+
+func TestFormatReaction(t *testing.T) {
+	r := Reaction{ReactionType: "UPVOTE", Count: 12}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{defaultReactionFormat, "UPVOTE: 12"},
+		{"{count}× {type}", "12× UPVOTE"},
+		{"👍 {count}", "👍 12"},
+	}
+	for _, tt := range tests {
+		if got := formatReaction(tt.format, r); got != tt.want {
+			t.Errorf("formatReaction(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestReactionFormatConfig(t *testing.T) {
+	t.Setenv("REACTION_FORMAT", "{count}× {type}")
+	cfg := testConfig(t)
+	article := testArticle("u1", "2024-05-01T10:00:00Z")
+	article.Reactions = []Reaction{{ReactionType: "UPVOTE", Count: 3}}
+
+	if html := generateHTMLEmail([]Article{article}, cfg.Location, cfg); !strings.Contains(html, "3× UPVOTE") {
+		t.Error("email does not use REACTION_FORMAT")
+	}
+
+	t.Setenv("REACTION_FORMAT", "reactions")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig accepted a REACTION_FORMAT without placeholders")
+	}
+}
+
+func TestMaxTagsShown(t *testing.T) {
+	inTempDir(t)
+	t.Setenv("MAX_TAGS_SHOWN", "2")
+	cfg := testConfig(t)
+
+	article := testArticle("u1", "2024-05-01T10:00:00Z")
+	for _, name := range []string{"Array", "Graph", "Heap", "Trie", "Stack"} {
+		article.Tags = append(article.Tags, Tag{Name: name, Slug: strings.ToLower(name), TagType: "TOPIC"})
+	}
+	articles := []Article{article}
+
+	html := generateHTMLEmail(articles, cfg.Location, cfg)
+	if !strings.Contains(html, "+3 more") || strings.Contains(html, ">Heap<") {
+		t.Error("email does not collapse tags beyond MAX_TAGS_SHOWN")
+	}
+
+	path, err := writeDigestFile(cfg, articles, cfg.Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := readFile(t, path); !strings.Contains(text, "  +3 more\n") || strings.Contains(text, "Heap") {
+		t.Errorf("file does not collapse tags beyond MAX_TAGS_SHOWN:\n%s", text)
+	}
+
+	jsonPath := "articles.json"
+	if err := writeArticlesToJSON(articles, jsonPath, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range article.Tags {
+		if !strings.Contains(readFile(t, jsonPath), `"`+tag.Name+`"`) {
+			t.Errorf("JSON output dropped tag %s", tag.Name)
+		}
+	}
+}
+
+func TestAnonymizeAuthors(t *testing.T) {
+	t.Setenv("ANONYMIZE_AUTHORS", "true")
+	cfg := testConfig(t)
+	dir := t.TempDir()
+
+	articles := []Article{
+		testArticle("u1", "2024-05-01T10:00:00Z"),
+		testArticle("u2", "2024-05-01T09:00:00Z"),
+		testArticle("u3", "2024-05-01T08:00:00Z"),
+	}
+	articles[0].Author.UserName = "alice_private"
+	articles[1].Author.UserName = "bob_private"
+	articles[2].Author.UserName = "alice_private"
+
+	alice, bob := authorName(articles[0], cfg), authorName(articles[1], cfg)
+	if alice != authorName(articles[2], cfg) {
+		t.Error("the same author got two pseudonyms")
+	}
+	if alice == bob {
+		t.Error("two authors share a pseudonym")
+	}
+	if !strings.HasPrefix(alice, "user-") || len(alice) != len("user-")+10 {
+		t.Errorf("pseudonym %q is not user-<hash>", alice)
+	}
+
+	outputs := map[string]string{
+		"html email":  generateHTMLEmail(articles, cfg.Location, cfg),
+		"plain email": generatePlainTextEmail(articles, cfg.Location, cfg),
+	}
+	writers := map[string]func([]Article, string, Config) error{
+		"text":     writeArticlesToFile,
+		"markdown": writeArticlesToMarkdown,
+		"json":     writeArticlesToJSON,
+		"rss":      writeArticlesToRSS,
+	}
+	for name, write := range writers {
+		path := filepath.Join(dir, name)
+		if err := write(articles, path, cfg); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		outputs[name] = readFile(t, path)
+	}
+
+	for name, out := range outputs {
+		if strings.Contains(out, "_private") {
+			t.Errorf("%s output contains a raw author name", name)
+		}
+		if !strings.Contains(out, alice) {
+			t.Errorf("%s output does not contain the pseudonym", name)
+		}
+	}
+}
+
+func TestParseArticleTime(t *testing.T) {
+	want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ts      string
+		wantErr bool
+	}{
+		{"2024-05-01T10:00:00Z", false},
+		{"2024-05-01T15:30:00+05:30", false},
+		{"2024-05-01 10:00:00", false},
+		{"2024-05-01T10:00:00", false},
+		{"1714557600", false},
+		{"1714557600000", false},
+		{"yesterday", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		got, err := parseArticleTime(tt.ts)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseArticleTime(%q) error = %v, want error %v", tt.ts, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !got.Equal(want) {
+			t.Errorf("parseArticleTime(%q) = %v, want %v", tt.ts, got, want)
+		}
+	}
+
+	// A naive timestamp is displayed as the UTC time converted to IST
+	if got := formatStringTimestamp("2024-05-01 10:00:00", "2006-01-02 15:04:05 MST", defaultLocation); got != "2024-05-01 15:30:00 IST" {
+		t.Errorf("naive timestamp formatted as %q, want 2024-05-01 15:30:00 IST", got)
+	}
+}
+
+func TestMergeReactions(t *testing.T) {
+	tests := []struct {
+		name      string
+		reactions []Reaction
+		want      []Reaction
+	}{
+		{"none", nil, []Reaction{}},
+		{"distinct", []Reaction{{3, "UPVOTE"}, {1, "AWESOME"}}, []Reaction{{3, "UPVOTE"}, {1, "AWESOME"}}},
+		{"duplicated", []Reaction{{3, "UPVOTE"}, {1, "AWESOME"}, {2, "UPVOTE"}}, []Reaction{{5, "UPVOTE"}, {1, "AWESOME"}}},
+		{"spelling differs", []Reaction{{1, "thumbs-up"}, {4, "THUMBS_UP"}}, []Reaction{{5, "thumbs-up"}}},
+	}
+	for _, tt := range tests {
+		if got := mergeReactions(tt.reactions); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: mergeReactions() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Both writers show the merged count once
+	cfg := testConfig(t)
+	article := testArticle("u1", "2024-05-01T10:00:00Z")
+	article.Reactions = []Reaction{{3, "UPVOTE"}, {2, "UPVOTE"}}
+	merged := formatReaction(cfg.ReactionFormat, Reaction{5, "UPVOTE"})
+
+	filename := filepath.Join(t.TempDir(), "digest.txt")
+	if err := writeArticlesToFile([]Article{article}, filename, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, filename); strings.Count(content, "UPVOTE") != 1 || !strings.Contains(content, merged) {
+		t.Errorf("file does not show one merged %q:\n%s", merged, content)
+	}
+	if html := generateHTMLEmail([]Article{article}, cfg.Location, cfg); strings.Count(html, "UPVOTE") != 1 || !strings.Contains(html, escapeHTML(merged)) {
+		t.Errorf("email does not show one merged %q", merged)
+	}
+}
+
+func TestEngagementCounts(t *testing.T) {
+	tests := []struct {
+		views, comments int
+		wantStats       string // the file's stats section, "" when left out
+		wantMeta        string // what follows the date on the email meta line
+	}{
+		{0, 0, "", "</div>"},
+		{120, 0, "\n--- Stats ---\nViews: 120\n", " • 120 views</div>"},
+		{0, 3, "\n--- Stats ---\nComments: 3\n", " • 3 comments</div>"},
+		{1, 1, "\n--- Stats ---\nViews: 1\nComments: 1\n", " • 1 view • 1 comment</div>"},
+	}
+	cfg := testConfig(t)
+	for _, tt := range tests {
+		article := testArticle("u1", "2024-05-01T10:00:00Z")
+		article.ViewCount, article.CommentCount = tt.views, tt.comments
+
+		filename := filepath.Join(t.TempDir(), "digest.txt")
+		if err := writeArticlesToFile([]Article{article}, filename, cfg); err != nil {
+			t.Fatal(err)
+		}
+		content := readFile(t, filename)
+		if tt.wantStats == "" && (strings.Contains(content, "--- Stats ---") || strings.Contains(content, ": 0\n")) {
+			t.Errorf("views=%d comments=%d: file shows zero counts:\n%s", tt.views, tt.comments, content)
+		}
+		if tt.wantStats != "" && !strings.Contains(content, tt.wantStats) {
+			t.Errorf("views=%d comments=%d: file lacks %q:\n%s", tt.views, tt.comments, tt.wantStats, content)
+		}
+
+		meta := "2024-05-01 15:30:00 IST" + tt.wantMeta
+		if html := generateHTMLEmail([]Article{article}, cfg.Location, cfg); !strings.Contains(html, meta) {
+			t.Errorf("views=%d comments=%d: email meta line lacks %q", tt.views, tt.comments, meta)
+		}
+	}
+}
+
+func TestPlainSummary(t *testing.T) {
+	summary := "  <p>Use a <b>min-heap</b></p><p>for Dijkstra &amp; Prim</p> &lt;3  "
+	tests := []struct {
+		env  string
+		want string
+	}{
+		{"", summary},
+		{"none", summary},
+		{"basic", "  Use a min-heapfor Dijkstra &amp; Prim &lt;3  "},
+		{"AGGRESSIVE", "Use a min-heap for Dijkstra & Prim <3"},
+	}
+	for _, tt := range tests {
+		t.Setenv("HTML_STRIP_MODE", tt.env)
+		cfg := testConfig(t)
+		if got := plainSummary(summary, cfg.HTMLStripMode); got != tt.want {
+			t.Errorf("HTML_STRIP_MODE=%q: plainSummary() = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+
+	t.Setenv("HTML_STRIP_MODE", "markdown")
+	if _, err := loadConfig(); err == nil {
+		t.Error("HTML_STRIP_MODE=markdown was accepted")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Header lines announcing how many articles follow, in the text and Markdown files
+var (
+	announcedTotal = regexp.MustCompile(`^(?:# )?LeetCode Discuss - (?:.+ digest - )?Latest (\d+) Articles(?: \(showing \d+ of \d+\))?$`)
+	announcedRun   = regexp.MustCompile(`^(?:# )?Run on: .* - (\d+) New Articles$`)
+)
+
+// Lines opening an article section: the "Article #N" header, optionally with a
+// rank, in text files and the UUID comment, empty for an article without one, in
+// Markdown. Each must follow the line
+// that precedes it in a real section, so summaries quoting them are not counted.
+var (
+	textSectionHeader     = regexp.MustCompile(`^Article #\d+(?: - .+)?$`)
+	markdownSectionHeader = regexp.MustCompile(`^` + regexp.QuoteMeta(markdownUUIDPrefix) + `\S* -->$`)
+)
+
+// verifyOutputFile re-reads a file that was just written and checks that it is
+// complete: it must end with a newline, and the article counts announced in its
+// headers must match the articles actually present.
+func verifyOutputFile(filename, format string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", filename, err)
+	}
+	if len(data) == 0 || data[len(data)-1] != '\n' {
+		return fmt.Errorf("verify %s: file is empty or truncated", filename)
+	}
+
+	if format == formatRSS {
+		var feed rssFeed
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			return fmt.Errorf("verify %s: %w", filename, err)
+		}
+		return nil
+	}
+
+	if format == formatJSON {
+		articles, err := readArticlesJSON(filename)
+		if err != nil {
+			return fmt.Errorf("verify %s: %w", filename, err)
+		}
+		for i, article := range articles {
+			if missing := missingFields(article); len(missing) > 0 {
+				return fmt.Errorf("verify %s: article %d is missing %s", filename, i+1, strings.Join(missing, ", "))
+			}
+		}
+		return nil
+	}
+
+	// Text sections open with a separator line and then the header; Markdown
+	// sections with the title heading and then the UUID comment
+	header, opensSection := textSectionHeader, isSeparatorLine
+	if format == formatMarkdown {
+		header = markdownSectionHeader
+		opensSection = func(line string) bool { return strings.HasPrefix(line, "## ") }
+	}
+
+	announced, found := 0, 0
+	previous := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		if m := announcedTotal.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			announced += n
+		} else if m := announcedRun.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			announced += n
+		} else if header.MatchString(line) && opensSection(previous) {
+			found++
+		}
+		previous = line
+	}
+
+	if found != announced {
+		return fmt.Errorf("verify %s: headers announce %d articles but %d were found", filename, announced, found)
+	}
+	return nil
+}
+
+// isSeparatorLine reports whether line is a "═" separator of the text file
+func isSeparatorLine(line string) bool {
+	return line != "" && strings.Trim(line, "═") == ""
+}
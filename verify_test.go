@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyOutputFile(t *testing.T) {
+	cfg := testConfig(t)
+	dir := t.TempDir()
+
+	quoting := testArticle("u2", "2024-05-01T09:00:00Z")
+	quoting.Summary = "Replying to the thread:\nArticle #3 - worth a read\nArticle #4"
+	articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z"), quoting, testArticle("u3", "2024-05-01T08:00:00Z")}
+
+	complete := filepath.Join(dir, "complete.txt")
+	if err := writeArticlesToFile(articles, complete, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyOutputFile(complete, formatText); err != nil {
+		t.Errorf("complete file failed verification: %v", err)
+	}
+
+	// Cut the file after the second section, at a line boundary so only the
+	// article count can give the truncation away
+	content := readFile(t, complete)
+	third := strings.Index(content, "UUID: u3\n")
+	cut := strings.LastIndex(content[:third], strings.Repeat("═", cfg.SeparatorWidth)+"\nArticle #3")
+	truncated := filepath.Join(dir, "truncated.txt")
+	writeFile(t, truncated, content[:cut])
+	if err := verifyOutputFile(truncated, formatText); err == nil || !strings.Contains(err.Error(), "announce 3 articles but 2 were found") {
+		t.Errorf("truncated file: verifyOutputFile() = %v, want a count mismatch", err)
+	}
+
+	markdown := filepath.Join(dir, "digest.md")
+	if err := writeArticlesToMarkdown(articles, markdown, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyOutputFile(markdown, formatMarkdown); err != nil {
+		t.Errorf("complete Markdown failed verification: %v", err)
+	}
+	data := readFile(t, markdown)
+	writeFile(t, markdown, data[:strings.Index(data, markdownUUIDPrefix+"u3")])
+	if err := verifyOutputFile(markdown, formatMarkdown); err == nil {
+		t.Error("truncated Markdown passed verification")
+	}
+
+	empty := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyOutputFile(empty, formatText); err == nil {
+		t.Error("empty file passed verification")
+	}
+}
+
+func TestVerifyArticlesWithoutUUID(t *testing.T) {
+	cfg := testConfig(t)
+	dir := t.TempDir()
+	articles := []Article{testArticle("u1", "2024-05-01T10:00:00Z"), testArticle("", "2024-05-01T09:00:00Z")}
+
+	writers := map[string]func([]Article, string, Config) error{
+		formatText:     writeArticlesToFile,
+		formatJSON:     writeArticlesToJSON,
+		formatMarkdown: writeArticlesToMarkdown,
+	}
+	for format, write := range writers {
+		filename := filepath.Join(dir, "digest."+format)
+		if err := write(articles, filename, cfg); err != nil {
+			t.Fatal(err)
+		}
+		if err := verifyOutputFile(filename, format); err != nil {
+			t.Errorf("%s: article without a UUID failed verification: %v", format, err)
+		}
+	}
+
+	// Fields needed for the title and link are still required
+	untitled := filepath.Join(dir, "untitled.json")
+	articles[1].Title = ""
+	if err := writeArticlesToJSON(articles, untitled, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyOutputFile(untitled, formatJSON); err == nil || !strings.Contains(err.Error(), "article 2 is missing title") {
+		t.Errorf("verifyOutputFile() = %v, want a missing title", err)
+	}
+}
+
+func TestVerifyTrimmedDigest(t *testing.T) {
+	for _, format := range []string{formatText, formatMarkdown} {
+		t.Run(format, func(t *testing.T) {
+			inTempDir(t)
+			t.Setenv("MAX_ARTICLES", "2")
+			t.Setenv("VERIFY_WRITE", "true")
+			cfg := testConfig(t)
+			cfg.Source, cfg.OutputFormat = "file:articles.json", format
+
+			now := time.Now().UTC().Truncate(time.Second)
+			var articles []Article
+			for i := range 3 {
+				articles = append(articles, testArticle(fmt.Sprintf("u%d", i), now.Add(-time.Duration(i+1)*time.Hour).Format(time.RFC3339)))
+			}
+			data, err := json.Marshal(articles)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeFile(t, "articles.json", string(data))
+
+			if code := runDigest(context.Background(), cfg, false); code != 0 {
+				t.Fatalf("runDigest() = %d, want 0", code)
+			}
+			files, _ := filepath.Glob(filepath.Join("fetched_articles", "leetcode_articles_*"))
+			if len(files) != 1 {
+				t.Fatalf("wrote %v, want one file", files)
+			}
+			if content := readFile(t, files[0]); !strings.Contains(content, "Latest 2 Articles (showing 2 of 3)\n") {
+				t.Errorf("header does not note the trimming:\n%s", content)
+			}
+			if err := verifyOutputFile(files[0], format); err != nil {
+				t.Errorf("trimmed digest failed verification: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultWeeklyReportFile collects the week's reported articles for the stats email
+const defaultWeeklyReportFile = "weekly_articles.json"
+
+// weeklyTopN is how many authors and tags the weekly report ranks
+const weeklyTopN = 5
+
+// parseWeekday validates a WEEKLY_REPORT_DAY value such as "monday" or "mon"
+func parseWeekday(v string) (time.Weekday, error) {
+	name := strings.ToLower(strings.TrimSpace(v))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || (len(name) >= 3 && strings.HasPrefix(full, name)) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown day %q: must be a day of the week such as monday", v)
+}
+
+// weeklyLog is the WEEKLY_REPORT_FILE: the articles reported since the last weekly
+// report was sent
+type weeklyLog struct {
+	Since    time.Time `json:"since"`
+	Articles []Article `json:"articles"`
+}
+
+// readWeeklyLog loads the weekly log, returning an empty one when the file does not exist
+func readWeeklyLog(path string) (weeklyLog, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return weeklyLog{}, nil
+	}
+	if err != nil {
+		return weeklyLog{}, fmt.Errorf("failed to read weekly report file: %w", err)
+	}
+	var log weeklyLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		return weeklyLog{}, fmt.Errorf("failed to parse weekly report file %s: %w", path, err)
+	}
+	return log, nil
+}
+
+// writeWeeklyLog saves the weekly log
+func writeWeeklyLog(path string, log weeklyLog, atomic bool) error {
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode weekly report file: %w", err)
+	}
+	return writeOutput(path, atomic, func(w io.Writer) {
+		w.Write(data)
+		w.Write([]byte("\n"))
+	})
+}
+
+// recordWeeklyArticles adds the new articles of a digest to the weekly log,
+// skipping any it already holds
+func recordWeeklyArticles(cfg Config, articles []Article, now time.Time) error {
+	log, err := readWeeklyLog(cfg.WeeklyReportFile)
+	if err != nil {
+		return err
+	}
+	if log.Since.IsZero() {
+		log.Since = now
+	}
+
+	seen := make(map[string]bool, len(log.Articles))
+	for _, article := range log.Articles {
+		seen[article.UUID] = true
+	}
+	log.Articles = append(log.Articles, excludeSeenArticles(newArticles(articles), seen)...)
+	return writeWeeklyLog(cfg.WeeklyReportFile, log, cfg.AtomicWrite)
+}
+
+// weeklyReportDue reports whether the weekly report should go out: it is the
+// configured day and the log was started before today
+func weeklyReportDue(log weeklyLog, day time.Weekday, now time.Time) bool {
+	if log.Since.IsZero() || now.Weekday() != day {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return log.Since.Before(today)
+}
+
+// rankedName is a name with how often it occurred
+type rankedName struct {
+	Name  string
+	Count int
+}
+
+// weeklyStats holds the aggregates of the weekly report
+type weeklyStats struct {
+	articleSummary
+	Since, Until    time.Time
+	BusiestDay      string // formatted date with the most articles, in the display time zone
+	BusiestDayCount int
+	TopAuthors      []rankedName
+	TopTags         []rankedName
+}
+
+// computeWeeklyStats aggregates the week's articles, counting days in loc
+func computeWeeklyStats(articles []Article, since, until time.Time, loc *time.Location, cfg Config) weeklyStats {
+	stats := weeklyStats{articleSummary: summarizeArticles(articles), Since: since, Until: until}
+
+	days := make(map[string]int)
+	authors := make(map[string]int)
+	tags := make(map[string]int)
+	for _, article := range articles {
+		if created, err := parseArticleTime(article.CreatedAt); err == nil {
+			days[created.In(loc).Format(cfg.DateFormat.Date)]++
+		}
+		authors[authorName(article, cfg)]++
+		for _, tag := range article.Tags {
+			tags[tag.Name]++
+		}
+	}
+
+	if busiest := topNames(days, 1); len(busiest) > 0 {
+		stats.BusiestDay, stats.BusiestDayCount = busiest[0].Name, busiest[0].Count
+	}
+	stats.TopAuthors = topNames(authors, weeklyTopN)
+	stats.TopTags = topNames(tags, weeklyTopN)
+	return stats
+}
+
+// topNames returns the n most frequent names, alphabetically first on a tie
+func topNames(counts map[string]int, n int) []rankedName {
+	ranked := make([]rankedName, 0, len(counts))
+	for name, count := range counts {
+		ranked = append(ranked, rankedName{name, count})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	return ranked[:min(n, len(ranked))]
+}
+
+// weeklyReportTitle is the heading and subject of the weekly report
+func weeklyReportTitle(label string) string {
+	return emailTitle(label) + " - Weekly Stats"
+}
+
+// generateWeeklyStatsText renders the plain-text weekly report
+func generateWeeklyStatsText(stats weeklyStats, cfg Config) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s\n", weeklyReportTitle(cfg.RunLabel))
+	fmt.Fprintf(&text, "%s to %s\n\n", stats.Since.In(cfg.Location).Format(cfg.DateFormat.Date), stats.Until.In(cfg.Location).Format(cfg.DateFormat.Date))
+
+	fmt.Fprintf(&text, "Articles: %d\n", stats.Count)
+	fmt.Fprintf(&text, "Authors: %d\n", stats.DistinctAuthors)
+	fmt.Fprintf(&text, "Reactions: %d\n", stats.TotalReactions)
+	if stats.BusiestDay != "" {
+		fmt.Fprintf(&text, "Busiest day: %s (%s)\n", stats.BusiestDay, plural(stats.BusiestDayCount, "article"))
+	}
+
+	for _, section := range []struct {
+		heading string
+		names   []rankedName
+	}{{"Top authors", stats.TopAuthors}, {"Top tags", stats.TopTags}} {
+		if len(section.names) == 0 {
+			continue
+		}
+		fmt.Fprintf(&text, "\n%s:\n", section.heading)
+		for i, name := range section.names {
+			fmt.Fprintf(&text, "%d. %s (%s)\n", i+1, name.Name, plural(name.Count, "article"))
+		}
+	}
+	return text.String()
+}
+
+// generateWeeklyStatsHTML renders the HTML weekly report
+func generateWeeklyStatsHTML(stats weeklyStats, cfg Config) string {
+	var html strings.Builder
+	html.WriteString(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Arial, sans-serif; color: #333; max-width: 560px; margin: 0 auto; padding: 40px 20px; background-color: #fff; }
+        h1 { font-size: 24px; font-weight: 600; color: #222; margin-bottom: 6px; }
+        h2 { font-size: 13px; text-transform: uppercase; letter-spacing: 1px; color: #999; margin: 32px 0 12px; }
+        .subtitle { color: #666; font-size: 14px; margin-bottom: 24px; }
+        .figures td { padding: 6px 24px 6px 0; font-size: 15px; }
+        .figures td.value { font-weight: 600; color: #222; }
+        ol { padding-left: 20px; margin: 0; }
+        li { font-size: 15px; margin-bottom: 6px; }
+        .count { color: #888; font-size: 13px; }
+        .footer { text-align: center; margin-top: 50px; padding-top: 20px; border-top: 1px solid #e5e5e5; color: #999; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <h1>` + escapeHTML(weeklyReportTitle(cfg.RunLabel)) + `</h1>
+    <div class="subtitle">` + fmt.Sprintf("%s to %s",
+		stats.Since.In(cfg.Location).Format(cfg.DateFormat.Date), stats.Until.In(cfg.Location).Format(cfg.DateFormat.Date)) + `</div>
+    <table class="figures">`)
+
+	figures := [][2]string{
+		{"Articles", fmt.Sprint(stats.Count)},
+		{"Authors", fmt.Sprint(stats.DistinctAuthors)},
+		{"Reactions", fmt.Sprint(stats.TotalReactions)},
+	}
+	if stats.BusiestDay != "" {
+		figures = append(figures, [2]string{"Busiest day", fmt.Sprintf("%s (%s)", stats.BusiestDay, plural(stats.BusiestDayCount, "article"))})
+	}
+	for _, figure := range figures {
+		html.WriteString(fmt.Sprintf(`
+        <tr><td>%s</td><td class="value">%s</td></tr>`, figure[0], escapeHTML(figure[1])))
+	}
+	html.WriteString(`
+    </table>`)
+
+	for _, section := range []struct {
+		heading string
+		names   []rankedName
+	}{{"Top authors", stats.TopAuthors}, {"Top tags", stats.TopTags}} {
+		if len(section.names) == 0 {
+			continue
+		}
+		html.WriteString(fmt.Sprintf(`
+    <h2>%s</h2>
+    <ol>`, section.heading))
+		for _, name := range section.names {
+			html.WriteString(fmt.Sprintf(`
+        <li>%s <span class="count">%s</span></li>`, escapeHTML(name.Name), plural(name.Count, "article")))
+		}
+		html.WriteString(`
+    </ol>`)
+	}
+
+	html.WriteString(`
+    <div class="footer">
+        <p>Weekly stats • LeetCode Articles Fetcher</p>
+    </div>
+</body>
+</html>`)
+	return html.String()
+}
+
+// sendWeeklyReport emails the weekly stats when they are due, then starts a new
+// week in the log. Failures are reported as warnings and leave the log as it is,
+// so the next run on the same day tries again.
+func sendWeeklyReport(ctx context.Context, cfg Config, now time.Time) {
+	log, err := readWeeklyLog(cfg.WeeklyReportFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	if !weeklyReportDue(log, cfg.WeeklyReportDay, now.In(cfg.Location)) {
+		return
+	}
+
+	stats := computeWeeklyStats(log.Articles, log.Since, now, cfg.Location, cfg)
+	msg := emailMessage{
+		FromEmail: cfg.FromEmail,
+		FromName:  cfg.FromName,
+		Subject:   fmt.Sprintf("📊 %s: %s", weeklyReportTitle(cfg.RunLabel), plural(stats.Count, "article")),
+		Text:      generateWeeklyStatsText(stats, cfg),
+		HTML:      generateWeeklyStatsHTML(stats, cfg),
+	}
+	if msg.FromName == "" {
+		msg.FromName = "LeetCode Articles Bot"
+	}
+	if err := cfg.Sender().Send(ctx, msg, cfg.ToEmails); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to send the weekly report: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Sent the weekly report covering %s\n", plural(stats.Count, "article"))
+
+	if err := writeWeeklyLog(cfg.WeeklyReportFile, weeklyLog{Since: now}, cfg.AtomicWrite); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWeeklyStatsEmail(t *testing.T) {
+	inTempDir(t)
+	cfg := testConfig(t)
+	cfg.Location = time.UTC
+	cfg.WeeklyReport, cfg.WeeklyReportDay, cfg.WeeklyReportFile = true, time.Monday, "weekly.json"
+	cfg.FromEmail, cfg.ToEmails = "bot@example.com", []string{"a@example.com"}
+	sender := &fakeSender{}
+	cfg.EmailSender = sender
+
+	articles := []Article{
+		testArticle("u1", "2024-05-01T10:00:00Z"),
+		testArticle("u2", "2024-05-01T09:00:00Z"),
+		testArticle("u3", "2024-05-02T08:00:00Z"),
+		testArticle("u4", "2024-05-03T07:00:00Z"),
+	}
+	articles[1].Author.UserName = "author-u1"
+	articles[2].Author.UserName = "author-u1"
+	articles[0].Tags = []Tag{{Name: "Graph"}, {Name: "Array"}}
+	articles[1].Tags = []Tag{{Name: "Array"}}
+	articles[3].Tags = []Tag{{Name: "Array"}}
+	articles[2].Reactions = []Reaction{{ReactionType: "UPVOTE", Count: 5}, {ReactionType: "AWESOME", Count: 2}}
+
+	since := time.Date(2024, 4, 29, 9, 0, 0, 0, time.UTC)
+	if err := writeWeeklyLog(cfg.WeeklyReportFile, weeklyLog{Since: since, Articles: articles}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Not due on a Sunday; due the following Monday
+	sendWeeklyReport(context.Background(), cfg, time.Date(2024, 5, 5, 9, 0, 0, 0, time.UTC))
+	if len(sender.sent) != 0 {
+		t.Fatalf("sent %d emails before the report day", len(sender.sent))
+	}
+	now := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	sendWeeklyReport(context.Background(), cfg, now)
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d emails on the report day, want 1", len(sender.sent))
+	}
+
+	msg := sender.sent[0].msg
+	if !strings.Contains(msg.Subject, "Weekly Stats: 4 articles") {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	busiest := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Format(cfg.DateFormat.Date)
+	for _, want := range []string{
+		"Articles: 4\n",
+		"Authors: 2\n",
+		"Reactions: 7\n",
+		"Busiest day: " + busiest + " (2 articles)\n",
+		"Top authors:\n1. author-u1 (3 articles)\n2. author-u4 (1 article)\n",
+		"Top tags:\n1. Array (3 articles)\n2. Graph (1 article)\n",
+	} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("text is missing %q:\n%s", want, msg.Text)
+		}
+	}
+	for _, want := range []string{"<td>Articles</td><td class=\"value\">4</td>", "author-u1 <span class=\"count\">3 articles</span>", "Array <span class=\"count\">3 articles</span>"} {
+		if !strings.Contains(msg.HTML, want) {
+			t.Errorf("HTML is missing %q", want)
+		}
+	}
+
+	// A sent report starts a new week
+	log, err := readWeeklyLog(cfg.WeeklyReportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !log.Since.Equal(now) || len(log.Articles) != 0 {
+		t.Errorf("log after sending = %+v, want an empty week since %v", log, now)
+	}
+}